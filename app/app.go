@@ -13,7 +13,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/theleeeo/docs-server/server"
+	"github.com/theleeeo/docs-server/cache"
 )
 
 const (
@@ -25,8 +25,19 @@ type App struct {
 	httpServer *http.Server
 	templates  map[string]*template.Template
 
-	cfg  *Config
-	serv *server.Server
+	cfg *Config
+
+	// The projects in the order they were configured
+	projects       []*Project
+	projectsByName map[string]*Project
+
+	// Closed when the http server is shutting down to end long-lived requests
+	shutdown chan struct{}
+
+	deliveries deliveries
+
+	// The compressed proxied files, so that they are not compressed on every request
+	compressed *cache.Memory
 
 	files struct {
 		headerImage *image
@@ -36,15 +47,26 @@ type App struct {
 	}
 }
 
-func New(cfg *Config, s *server.Server) (*App, error) {
+func New(cfg *Config, projects []*Project) (*App, error) {
 	if err := validateConfig(cfg); err != nil {
 		return nil, err
 	}
 
+	if err := validateProjects(projects); err != nil {
+		return nil, err
+	}
+
 	a := &App{
-		templates: make(map[string]*template.Template),
-		cfg:       cfg,
-		serv:      s,
+		templates:      make(map[string]*template.Template),
+		cfg:            cfg,
+		projects:       projects,
+		projectsByName: make(map[string]*Project, len(projects)),
+		shutdown:       make(chan struct{}),
+		compressed:     newCompressedCache(),
+	}
+
+	for _, p := range projects {
+		a.projectsByName[p.Name] = p
 	}
 
 	if cfg.Favicon != "" {
@@ -84,6 +106,9 @@ func New(cfg *Config, s *server.Server) (*App, error) {
 		Addr:    a.cfg.Address,
 		Handler: mux,
 	}
+	a.httpServer.RegisterOnShutdown(func() {
+		close(a.shutdown)
+	})
 
 	return a, nil
 }
@@ -129,13 +154,26 @@ func registerHandlers(mux *http.ServeMux, a *App) {
 
 	mux.HandleFunc("GET "+a.route("/header-image"), a.getHeaderImageHandler)
 	mux.HandleFunc("GET "+a.route("/favicon.ico"), a.getFaviconHandler)
-	mux.HandleFunc("GET "+a.rootRoute(), a.getIndexHandler)
 	mux.HandleFunc("GET "+a.route("/script.js"), a.getScriptHandler)
 	mux.HandleFunc("GET "+a.route("/style.css"), a.getStyleHandler)
-	mux.HandleFunc("GET "+a.route("/versions"), a.getVersionsHandler)
-	mux.HandleFunc("GET "+a.route("/version/{version}/roles"), a.getRolesHandler)
-	mux.HandleFunc("GET "+a.route("/{version}/{role...}"), a.renderDocHandler)
-	mux.HandleFunc("GET "+a.route("/proxy/{version}/{file...}"), a.proxyHandler)
+
+	if a.multiProject() {
+		mux.HandleFunc("GET "+a.rootRoute(), a.getProjectsIndexHandler)
+		mux.HandleFunc("GET "+a.route("/projects"), a.getProjectsHandler)
+		mux.HandleFunc("GET "+a.route("/{project}"), a.withProject(a.redirectToProjectHandler))
+	}
+
+	mux.HandleFunc("GET "+a.projectRoute("/{$}"), a.withProject(a.getIndexHandler))
+	mux.HandleFunc("GET "+a.projectRoute("/versions"), a.withProject(a.getVersionsHandler))
+	mux.HandleFunc("GET "+a.projectRoute("/version/{version}/roles"), a.withProject(a.getRolesHandler))
+	mux.HandleFunc("GET "+a.projectRoute("/events"), a.withProject(a.eventsHandler))
+	mux.HandleFunc("GET "+a.projectRoute("/status"), a.withProject(a.getStatusHandler))
+	mux.HandleFunc("GET "+a.projectRoute("/{version}/{role...}"), a.withProject(a.renderDocHandler))
+	mux.HandleFunc("GET "+a.projectRoute("/proxy/{version}/{file...}"), a.withProject(a.proxyHandler))
+
+	if a.cfg.WebhookSecret != "" {
+		mux.HandleFunc("POST "+a.projectRoute("/webhook/github"), a.withProject(a.githubWebhookHandler))
+	}
 }
 
 func validateConfig(cfg *Config) error {
@@ -157,6 +195,10 @@ func validateConfig(cfg *Config) error {
 	}
 	cfg.PathPrefix = strings.TrimRight(cfg.PathPrefix, "/")
 
+	if cfg.WebhookSecret == "" {
+		slog.Info("no webhook secret set, webhooks are disabled")
+	}
+
 	if cfg.HeaderColor == "" {
 		cfg.HeaderColor = "none"
 	}
@@ -216,6 +258,7 @@ func (a *App) loadStyle() error {
 
 func (a *App) loadTemplates() error {
 	pages := map[string]string{
+		"project-select": filepath.Join("views", "project-select.html"),
 		"version-select": filepath.Join("views", "version-select.html"),
 		"doc":            filepath.Join("views", "doc.html"),
 	}
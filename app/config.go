@@ -9,4 +9,7 @@ type Config struct {
 	Favicon     string
 
 	PathPrefix string
+
+	// The secret used to verify GitHub webhook deliveries
+	WebhookSecret string
 }
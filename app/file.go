@@ -0,0 +1,201 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/theleeeo/docs-server/cache"
+)
+
+const (
+	// Files smaller than this are not worth compressing
+	minCompressSize = 1024
+	// The total size of the compressed files kept for later requests
+	compressedCacheBytes = 64 << 20
+
+	// Tags are not expected to change, but they can be moved, so they are not cached forever
+	immutableCacheControl = "public, max-age=86400"
+	// Moving versions are always revalidated, which is cheap thanks to the ETag
+	movingCacheControl = "no-cache"
+)
+
+// serveFile writes a proxied file with caching headers, answering conditional requests
+// with 304 Not Modified and compressing the body if the client accepts it.
+// Files requested through an alias are always revalidated since the alias moves to newer versions.
+func (a *App) serveFile(w http.ResponseWriter, r *http.Request, p *Project, version string, alias bool, data []byte) {
+	contentType := http.DetectContentType(data)
+	encoding := ""
+	if len(data) >= minCompressSize && compressible(contentType) {
+		encoding = negotiateEncoding(r.Header.Get("Accept-Encoding"))
+	}
+
+	etag := fileETag(data, encoding)
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Vary", "Accept-Encoding")
+
+	var updated time.Time
+	if doc := p.Server.GetVersion(version); doc != nil && !doc.Moving && !alias {
+		h.Set("Cache-Control", immutableCacheControl)
+		updated = doc.Updated
+	} else {
+		h.Set("Cache-Control", movingCacheControl)
+		if doc != nil {
+			updated = doc.Updated
+		}
+	}
+	if !updated.IsZero() {
+		h.Set("Last-Modified", updated.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, updated) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.Set("Content-Type", contentType)
+
+	if encoding == "" {
+		h.Set("Content-Length", strconv.Itoa(len(data)))
+		_, _ = w.Write(data)
+		return
+	}
+
+	compressed, err := a.compressCached(data, encoding, etag)
+	if err != nil {
+		slog.Error("failed to compress file", "encoding", encoding, "error", err)
+		h.Set("ETag", fileETag(data, ""))
+		_, _ = w.Write(data)
+		return
+	}
+
+	h.Set("Content-Encoding", encoding)
+	h.Set("Content-Length", strconv.Itoa(len(compressed)))
+	_, _ = w.Write(compressed)
+}
+
+// fileETag returns a strong ETag of the data.
+// Each encoding is a different representation and therefore gets its own ETag.
+func fileETag(data []byte, encoding string) string {
+	sum := sha256.Sum256(data)
+	tag := hex.EncodeToString(sum[:16])
+	if encoding != "" {
+		tag += "-" + encoding
+	}
+
+	return `"` + tag + `"`
+}
+
+// notModified evaluates the conditional headers of the request.
+// If-Modified-Since is only used if If-None-Match is not present.
+func notModified(r *http.Request, etag string, updated time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !updated.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err == nil && !updated.Truncate(time.Second).After(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// negotiateEncoding picks the preferred supported encoding from an Accept-Encoding header.
+// Brotli is preferred over gzip when both are equally accepted.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if q <= 0 || (name != "br" && name != "gzip") {
+			continue
+		}
+
+		if q > bestQ || (q == bestQ && name == "br") {
+			best, bestQ = name, q
+		}
+	}
+
+	return best
+}
+
+func compressible(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "yaml") ||
+		strings.Contains(contentType, "xml") ||
+		strings.Contains(contentType, "javascript")
+}
+
+// compressCached compresses each content once per encoding, the ETag identifies both.
+func (a *App) compressCached(data []byte, encoding, etag string) ([]byte, error) {
+	if compressed, _ := a.compressed.Get(encoding, "", etag); compressed != nil {
+		return compressed, nil
+	}
+
+	compressed, err := compress(data, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.compressed.Set(encoding, "", etag, compressed); err != nil {
+		slog.Warn("failed to keep compressed file", "error", err)
+	}
+
+	return compressed, nil
+}
+
+func newCompressedCache() *cache.Memory {
+	return cache.NewMemory(&cache.Config{MaxBytes: compressedCacheBytes})
+}
+
+func compress(data []byte, encoding string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var w io.WriteCloser
+	switch encoding {
+	case "br":
+		w = brotli.NewWriterLevel(&buf, brotli.DefaultCompression)
+	default:
+		w = gzip.NewWriter(&buf)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/theleeeo/docs-server/provider"
+	"github.com/theleeeo/docs-server/server"
+)
+
+type stubProvider struct{}
+
+func (stubProvider) ListVersions(ctx context.Context) ([]provider.Version, error) {
+	return nil, nil
+}
+
+func (stubProvider) ListFiles(ctx context.Context, version string) ([]string, error) {
+	return []string{"api"}, nil
+}
+
+func (stubProvider) GetPath(version, file string) string {
+	return ""
+}
+
+func (stubProvider) DownloadFile(ctx context.Context, version, file string) ([]byte, error) {
+	return nil, provider.ErrNotFound
+}
+
+func newTestProject(t *testing.T, versions ...provider.Version) *Project {
+	t.Helper()
+
+	s, err := server.New(&server.Config{}, stubProvider{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range versions {
+		if err := s.FetchVersion(context.Background(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &Project{Server: s}
+}
+
+func TestServeFileCacheControl(t *testing.T) {
+	p := newTestProject(t,
+		provider.Version{Name: "v1.0.0", Revision: "a1"},
+		provider.Version{Name: "main", Revision: "b2", Moving: true},
+	)
+	a := &App{compressed: newCompressedCache()}
+
+	tests := []struct {
+		name      string
+		requested string
+		want      string
+	}{
+		{name: "tag", requested: "v1.0.0", want: immutableCacheControl},
+		{name: "branch", requested: "main", want: movingCacheControl},
+		{name: "latest alias of a tag", requested: server.LatestAlias, want: movingCacheControl},
+		{name: "latest stable alias of a tag", requested: server.LatestStableAlias, want: movingCacheControl},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version := p.Server.ResolveVersion(tt.requested)
+
+			w := httptest.NewRecorder()
+			a.serveFile(w, httptest.NewRequest(http.MethodGet, "/", nil), p, version, version != tt.requested, []byte("{}"))
+
+			if got := w.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeFileCompressesOnce(t *testing.T) {
+	p := newTestProject(t, provider.Version{Name: "v1.0.0", Revision: "a1"})
+	a := &App{compressed: newCompressedCache()}
+	data := []byte(`{"paths":"` + strings.Repeat("a", 2*minCompressSize) + `"}`)
+
+	for _, encoding := range []string{"gzip", "br", "gzip"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", encoding)
+
+		w := httptest.NewRecorder()
+		a.serveFile(w, r, p, "v1.0.0", false, data)
+
+		if got := w.Header().Get("Content-Encoding"); got != encoding {
+			t.Fatalf("got encoding %q, want %q", got, encoding)
+		}
+	}
+
+	// The second gzip request reuses the first one
+	stats := a.compressed.Stats()
+	if stats.Entries != 2 || stats.Hits != 1 {
+		t.Errorf("got %d compressed files and %d hits, want 2 and 1", stats.Entries, stats.Hits)
+	}
+}
@@ -40,45 +40,73 @@ func (a *App) getStyleHandler(w http.ResponseWriter, r *http.Request) {
 	_, _ = io.WriteString(w, a.files.style)
 }
 
-func (a *App) getIndexHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) getProjectsIndexHandler(w http.ResponseWriter, r *http.Request) {
+	a.render(w, "project-select", map[string]any{
+		"HeaderTitle": a.cfg.HeaderTitle,
+		"Favicon":     a.cfg.Favicon,
+		"PathPrefix":  a.cfg.PathPrefix,
+		"HasTitle":    a.cfg.HeaderTitle != "",
+		"HasImage":    a.cfg.HeaderImage != "",
+		"Projects":    a.projectNames(),
+	})
+}
+
+func (a *App) getProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	a.writeJSON(w, a.projectNames())
+}
+
+func (a *App) getIndexHandler(w http.ResponseWriter, r *http.Request, p *Project) {
 	a.render(w, "version-select", map[string]any{
 		"HeaderTitle": a.cfg.HeaderTitle,
 		"Favicon":     a.cfg.Favicon,
 		"PathPrefix":  a.cfg.PathPrefix,
+		"BasePath":    a.basePath(p),
+		"Project":     p.Name,
 		"HasTitle":    a.cfg.HeaderTitle != "",
 		"HasImage":    a.cfg.HeaderImage != "",
 	})
 }
 
-func (a *App) renderDocHandler(w http.ResponseWriter, r *http.Request) {
-	version := r.PathValue("version")
+func (a *App) renderDocHandler(w http.ResponseWriter, r *http.Request, p *Project) {
+	version := p.Server.ResolveVersion(r.PathValue("version"))
 	role := r.PathValue("role")
 
 	var path string
-	if a.serv.ProxyEnabled() {
-		path = fmt.Sprint(a.cfg.PathPrefix, "/proxy/", version, "/", role)
+	if p.Server.ServedByProxy(version, role) {
+		path = fmt.Sprint(a.basePath(p), "/proxy/", version, "/", role)
 	} else {
-		path = a.serv.Path(version, role)
+		path = p.Server.Path(version, role)
 	}
 
 	a.render(w, "doc", map[string]any{
 		"HeaderTitle": a.cfg.HeaderTitle,
 		"Favicon":     a.cfg.Favicon,
 		"PathPrefix":  a.cfg.PathPrefix,
+		"BasePath":    a.basePath(p),
+		"Project":     p.Name,
 		"HasTitle":    a.cfg.HeaderTitle != "",
 		"HasImage":    a.cfg.HeaderImage != "",
 		"Path":        path,
+		"Version":     version,
+		"LiveReload":  p.Server.Watching(),
 	})
 }
 
-func (a *App) getVersionsHandler(w http.ResponseWriter, r *http.Request) {
-	a.writeJSON(w, a.serv.GetVersions())
+func (a *App) getVersionsHandler(w http.ResponseWriter, r *http.Request, p *Project) {
+	a.writeJSON(w, p.Server.GetVersions())
+}
+
+func (a *App) getStatusHandler(w http.ResponseWriter, r *http.Request, p *Project) {
+	a.writeJSON(w, map[string]any{
+		"cache":      p.Server.CacheStatus(),
+		"rate_limit": p.Server.RateLimitStatus(),
+	})
 }
 
-func (a *App) getRolesHandler(w http.ResponseWriter, r *http.Request) {
-	version := r.PathValue("version")
+func (a *App) getRolesHandler(w http.ResponseWriter, r *http.Request, p *Project) {
+	version := p.Server.ResolveVersion(r.PathValue("version"))
 
-	doc := a.serv.GetVersion(version)
+	doc := p.Server.GetVersion(version)
 	if doc == nil {
 		http.Error(w, "404 Version Not Found", http.StatusNotFound)
 		return
@@ -87,35 +115,82 @@ func (a *App) getRolesHandler(w http.ResponseWriter, r *http.Request) {
 	a.writeJSON(w, doc.Files)
 }
 
-func (a *App) proxyHandler(w http.ResponseWriter, r *http.Request) {
-	if !a.serv.ProxyEnabled() {
+func (a *App) proxyHandler(w http.ResponseWriter, r *http.Request, p *Project) {
+	requested := r.PathValue("version")
+	version := p.Server.ResolveVersion(requested)
+	file := r.PathValue("file")
+
+	if !p.Server.ServedByProxy(version, file) {
 		http.NotFound(w, r)
 		return
 	}
 
-	version := r.PathValue("version")
-	file := r.PathValue("file")
-
-	data, err := a.serv.GetFile(r.Context(), version, file)
+	data, err := p.Server.GetFile(r.Context(), version, file)
 	if err != nil {
 		if errors.Is(err, server.ErrNotFound) {
 			http.NotFound(w, r)
 			return
 		}
 
-		slog.Error("failed to get file from proxy", "error", err)
+		slog.Error("failed to get file from proxy", "project", p.Name, "error", err)
 		http.Error(w, "An error occurred, please try again later.", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", http.DetectContentType(data))
-	_, _ = w.Write(data)
+	a.serveFile(w, r, p, version, version != requested, data)
+}
+
+// eventsHandler streams the versions that changed as server-sent events.
+func (a *App) eventsHandler(w http.ResponseWriter, r *http.Request, p *Project) {
+	if !p.Server.Watching() {
+		http.NotFound(w, r)
+		return
+	}
+
+	events, unsubscribe := p.Server.Subscribe()
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		slog.Error("failed to flush events", "error", err)
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.shutdown:
+			return
+		case version := <-events:
+			data, err := json.Marshal(map[string]string{"version": version})
+			if err != nil {
+				slog.Error("failed to encode event", "error", err)
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
 }
 
 func (a *App) redirectToRootHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, a.cfg.PathPrefix+"/", http.StatusMovedPermanently)
 }
 
+func (a *App) redirectToProjectHandler(w http.ResponseWriter, r *http.Request, p *Project) {
+	http.Redirect(w, r, a.basePath(p)+"/", http.StatusMovedPermanently)
+}
+
 func (a *App) render(w http.ResponseWriter, name string, data map[string]any) {
 	t, ok := a.templates[name]
 	if !ok {
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/theleeeo/docs-server/server"
+)
+
+// Project is a set of documentation served by its own server.
+type Project struct {
+	// The name of the project used in the urls.
+	// It is empty if the app only serves a single project without a name.
+	Name   string
+	Server *server.Server
+}
+
+type projectHandlerFunc func(w http.ResponseWriter, r *http.Request, p *Project)
+
+// reservedProjectNames clash with the routes of the app, the projects would not be reachable.
+var reservedProjectNames = []string{
+	"projects",
+	"script.js",
+	"style.css",
+	"favicon.ico",
+	"header-image",
+	"webhook",
+	"status",
+	"events",
+}
+
+func validateProjects(projects []*Project) error {
+	if len(projects) == 0 {
+		return fmt.Errorf("no projects configured")
+	}
+
+	if len(projects) == 1 && projects[0].Name == "" {
+		return nil
+	}
+
+	seen := make(map[string]struct{}, len(projects))
+	for _, p := range projects {
+		if p.Name == "" {
+			return fmt.Errorf("project name cannot be empty when serving multiple projects")
+		}
+
+		if strings.ContainsAny(p.Name, "/?#") {
+			return fmt.Errorf("invalid project name: %s", p.Name)
+		}
+
+		if slices.Contains(reservedProjectNames, p.Name) {
+			return fmt.Errorf("reserved project name: %s", p.Name)
+		}
+
+		if _, ok := seen[p.Name]; ok {
+			return fmt.Errorf("duplicate project name: %s", p.Name)
+		}
+		seen[p.Name] = struct{}{}
+	}
+
+	return nil
+}
+
+// multiProject reports if the projects are served under their names.
+func (a *App) multiProject() bool {
+	return a.projects[0].Name != ""
+}
+
+// projectRoute returns the route of a path within a project.
+func (a *App) projectRoute(path string) string {
+	if !a.multiProject() {
+		return a.route(path)
+	}
+
+	return a.route("/{project}" + path)
+}
+
+// basePath returns the path that all urls of a project start with.
+func (a *App) basePath(p *Project) string {
+	if p.Name == "" {
+		return a.cfg.PathPrefix
+	}
+
+	return fmt.Sprint(a.cfg.PathPrefix, "/", p.Name)
+}
+
+// withProject resolves the project of the request before calling the handler.
+func (a *App) withProject(h projectHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := a.projects[0]
+		if a.multiProject() {
+			var ok bool
+			p, ok = a.projectsByName[r.PathValue("project")]
+			if !ok {
+				http.Error(w, "404 Project Not Found", http.StatusNotFound)
+				return
+			}
+		}
+
+		h(w, r, p)
+	}
+}
+
+func (a *App) projectNames() []string {
+	names := make([]string, len(a.projects))
+	for i, p := range a.projects {
+		names[i] = p.Name
+	}
+
+	return names
+}
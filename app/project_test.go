@@ -0,0 +1,35 @@
+package app
+
+import "testing"
+
+func TestValidateProjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		wantErr bool
+	}{
+		{name: "single unnamed", names: []string{""}},
+		{name: "multiple named", names: []string{"billing", "users"}},
+		{name: "none", names: nil, wantErr: true},
+		{name: "unnamed among named", names: []string{"billing", ""}, wantErr: true},
+		{name: "duplicate", names: []string{"billing", "billing"}, wantErr: true},
+		{name: "slash", names: []string{"billing/v1"}, wantErr: true},
+		{name: "clashes with the projects route", names: []string{"projects"}, wantErr: true},
+		{name: "clashes with a static file", names: []string{"billing", "script.js"}, wantErr: true},
+		{name: "clashes with the webhook route", names: []string{"webhook"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var projects []*Project
+			for _, name := range tt.names {
+				projects = append(projects, &Project{Name: name})
+			}
+
+			err := validateProjects(projects)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// GitHub caps webhook payloads at 25MB
+	maxWebhookBodySize = 25 << 20
+	// How many delivery IDs to remember for deduplication
+	maxRememberedDeliveries = 1000
+)
+
+// githubWebhookHandler triggers a poll when GitHub reports that a ref was pushed, created or deleted.
+func (a *App) githubWebhookHandler(w http.ResponseWriter, r *http.Request, p *Project) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if !validSignature(a.cfg.WebhookSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+		slog.Warn("webhook with invalid signature received")
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	deliveryID := r.Header.Get("X-GitHub-Delivery")
+	if deliveryID != "" && a.deliveries.has(deliveryID) {
+		slog.Debug("duplicate webhook delivery ignored", "delivery", deliveryID)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	switch event {
+	case "ping":
+		slog.Info("webhook ping received")
+	case "push", "create", "delete":
+		var payload struct {
+			Ref     string `json:"ref"`
+			RefType string `json:"ref_type"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		slog.Info("webhook received, triggering poll", "project", p.Name, "event", event, "ref", strings.TrimPrefix(payload.Ref, "refs/"), "delivery", deliveryID)
+		p.Server.TriggerPoll()
+	default:
+		slog.Debug("ignoring webhook event", "event", event)
+	}
+
+	// Only handled deliveries are remembered, so that a redelivery of a failed one is not ignored
+	if deliveryID != "" {
+		a.deliveries.add(deliveryID)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// validSignature reports if the signature is a valid HMAC of the body using the secret.
+func validSignature(secret string, body []byte, signature string) bool {
+	signature, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// deliveries remembers the most recent webhook delivery IDs.
+type deliveries struct {
+	lock sync.Mutex
+	seen map[string]struct{}
+	// The IDs in the order they were seen, used to forget the oldest ones
+	order []string
+}
+
+// has reports if the ID was seen before.
+func (d *deliveries) has(id string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	_, ok := d.seen[id]
+	return ok
+}
+
+// add adds the ID and reports if it was not seen before.
+func (d *deliveries) add(id string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.seen == nil {
+		d.seen = make(map[string]struct{})
+	}
+
+	if _, ok := d.seen[id]; ok {
+		return false
+	}
+
+	if len(d.order) >= maxRememberedDeliveries {
+		delete(d.seen, d.order[0])
+		d.order = d.order[1:]
+	}
+
+	d.seen[id] = struct{}{}
+	d.order = append(d.order, id)
+
+	return true
+}
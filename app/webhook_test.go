@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/theleeeo/docs-server/provider"
+	"github.com/theleeeo/docs-server/server"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidSignature(t *testing.T) {
+	body := `{"ref":"refs/heads/main"}`
+
+	tests := []struct {
+		name      string
+		signature string
+		want      bool
+	}{
+		{name: "valid", signature: sign("secret", body), want: true},
+		{name: "missing", signature: ""},
+		{name: "missing prefix", signature: strings.TrimPrefix(sign("secret", body), "sha256=")},
+		{name: "sha1 prefix", signature: "sha1=" + strings.TrimPrefix(sign("secret", body), "sha256=")},
+		{name: "bad hex", signature: "sha256=not-hex"},
+		{name: "wrong secret", signature: sign("other", body)},
+		{name: "other body", signature: sign("secret", body+" ")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validSignature("secret", []byte(body), tt.signature); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGithubWebhookHandler(t *testing.T) {
+	a := &App{cfg: &Config{WebhookSecret: "secret"}}
+	p := newTestProject(t)
+
+	push := `{"ref":"refs/heads/main"}`
+
+	// The deliveries are sent in order to the same app
+	tests := []struct {
+		name           string
+		event          string
+		delivery       string
+		body           string
+		signature      string
+		wantStatus     int
+		wantRemembered bool
+	}{
+		{name: "push", event: "push", delivery: "1", body: push, signature: sign("secret", push), wantStatus: http.StatusOK, wantRemembered: true},
+		{name: "duplicate delivery", event: "push", delivery: "1", body: "{", signature: sign("secret", "{"), wantStatus: http.StatusOK, wantRemembered: true},
+		{name: "invalid signature", event: "push", delivery: "2", body: push, signature: sign("other", push), wantStatus: http.StatusUnauthorized},
+		{name: "redelivery after an invalid signature", event: "create", delivery: "2", body: push, signature: sign("secret", push), wantStatus: http.StatusOK, wantRemembered: true},
+		{name: "invalid payload", event: "push", delivery: "3", body: "{", signature: sign("secret", "{"), wantStatus: http.StatusBadRequest},
+		{name: "redelivery of an invalid payload", event: "push", delivery: "3", body: push, signature: sign("secret", push), wantStatus: http.StatusOK, wantRemembered: true},
+		{name: "ping", event: "ping", delivery: "4", body: `{}`, signature: sign("secret", `{}`), wantStatus: http.StatusOK, wantRemembered: true},
+		{name: "other event", event: "issues", delivery: "5", body: `{}`, signature: sign("secret", `{}`), wantStatus: http.StatusOK, wantRemembered: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			a.githubWebhookHandler(w, newWebhookRequest(tt.event, tt.delivery, tt.body, tt.signature), p)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if got := a.deliveries.has(tt.delivery); got != tt.wantRemembered {
+				t.Errorf("got delivery remembered %t, want %t", got, tt.wantRemembered)
+			}
+		})
+	}
+}
+
+func newWebhookRequest(event, delivery, body, signature string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	r.Header.Set("X-GitHub-Event", event)
+	r.Header.Set("X-GitHub-Delivery", delivery)
+	r.Header.Set("X-Hub-Signature-256", signature)
+
+	return r
+}
+
+// pollingProvider reports every listing of the versions, which is what a poll does.
+type pollingProvider struct {
+	stubProvider
+	polls chan struct{}
+}
+
+func (p pollingProvider) ListVersions(ctx context.Context) ([]provider.Version, error) {
+	p.polls <- struct{}{}
+	return nil, nil
+}
+
+func TestGithubWebhookHandlerTriggersPoll(t *testing.T) {
+	polls := make(chan struct{}, 1)
+	s, err := server.New(&server.Config{PollInterval: time.Hour}, pollingProvider{polls: polls})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.Run(ctx)
+	<-polls
+
+	a := &App{cfg: &Config{WebhookSecret: "secret"}}
+	push := `{"ref":"refs/tags/v1.0.0","ref_type":"tag"}`
+
+	w := httptest.NewRecorder()
+	a.githubWebhookHandler(w, newWebhookRequest("create", "1", push, sign("secret", push)), &Project{Server: s})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d", w.Code)
+	}
+
+	select {
+	case <-polls:
+	case <-time.After(5 * time.Second):
+		t.Error("no poll was triggered")
+	}
+}
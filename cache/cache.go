@@ -1,41 +1,49 @@
 package cache
 
-import "fmt"
-
-// TODO: Cache eviction
-type Cache struct {
-	cache map[string][]byte
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Cache stores the files downloaded by the proxy.
+type Cache interface {
+	// Get returns the cached file, or nil if it is not cached at the revision of the version.
+	Get(version, revision, file string) ([]byte, error)
+	// GetStale returns the cached file even if it has expired or is of another revision, or nil if it is not cached.
+	// It is used to serve the last good copy when the file can not be downloaded.
+	GetStale(version, file string) ([]byte, error)
+	// Set stores a file downloaded at the revision of the version.
+	Set(version, revision, file string, data []byte) error
+	// Has reports if a file is cached at the revision without counting as a use of it.
+	Has(version, revision, file string) bool
+	// RemoveVersion removes all files of a version from the cache.
+	RemoveVersion(version string)
+	// Stats returns the current counters of the cache.
+	Stats() Stats
 }
 
-func New() *Cache {
-	return &Cache{
-		cache: make(map[string][]byte),
-	}
-}
+// Stats are the counters of a cache.
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	// The number of times an expired file was served because it could not be downloaded
+	StaleHits uint64 `json:"stale_hits"`
 
-func getCacheKey(version, file string) string {
-	return fmt.Sprint(version, "/", file)
+	Entries int   `json:"entries"`
+	Bytes   int64 `json:"bytes"`
 }
 
-func (p *Cache) loadFromCache(version, file string) ([]byte, error) {
-	key := getCacheKey(version, file)
-	data, ok := p.cache[key]
-	if !ok {
-		return nil, nil
+// New creates a cache on disk if a directory is configured, otherwise in memory.
+func New(cfg *Config) (Cache, error) {
+	if cfg.Dir != "" {
+		slog.Info("using disk cache", "dir", cfg.Dir)
+		return NewDisk(cfg)
 	}
-	return data, nil
-}
-
-func (p *Cache) saveToCache(version, file string, data []byte) {
-	key := getCacheKey(version, file)
-	p.cache[key] = data
-}
 
-func (p *Cache) Get(version, file string) ([]byte, error) {
-	return p.loadFromCache(version, file)
+	return NewMemory(cfg), nil
 }
 
-func (p *Cache) Set(version, file string, data []byte) error {
-	p.saveToCache(version, file, data)
-	return nil
+func getCacheKey(version, file string) string {
+	return fmt.Sprint(version, "/", file)
 }
@@ -0,0 +1,16 @@
+package cache
+
+import "time"
+
+type Config struct {
+	// The maximum total size of the cached files in bytes, 0 means unlimited
+	MaxBytes int64
+	// The maximum number of cached files, 0 means unlimited
+	MaxEntries int
+	// How long a file is cached before it has to be downloaded again, 0 means forever
+	TTL time.Duration
+	// The directory to store the cached files in, they are kept in memory if empty
+	Dir string
+	// Separates the files of the projects sharing the directory, they are stored in a subdirectory with this name
+	Namespace string
+}
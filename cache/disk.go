@@ -0,0 +1,325 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// The first line of every cached file, changed if the format ever changes
+	diskFormatHeader = "docs-server-cache v2"
+	diskFileSuffix   = ".cache"
+	diskTempPrefix   = ".tmp-"
+)
+
+// Disk is a cache storing the files in a directory so that they survive restarts.
+// It evicts the least recently used files when it grows beyond its limits.
+//
+// Each file is stored with the key, the revision it was downloaded at and a checksum of its data,
+// which are validated when the cache is loaded and when a file is read.
+// Files of a revision other than the current one of their version are misses,
+// so that versions that moved while the server was stopped are downloaded again.
+//
+// It is safe for concurrent use.
+type Disk struct {
+	// Held while accessing the index and the files so that they stay consistent
+	lock sync.Mutex
+
+	dir   string
+	index *index
+}
+
+func NewDisk(cfg *Config) (*Disk, error) {
+	dir := cfg.Dir
+	if cfg.Namespace != "" {
+		if !filepath.IsLocal(cfg.Namespace) || strings.ContainsAny(cfg.Namespace, `/\`) {
+			return nil, fmt.Errorf("invalid cache namespace: %q", cfg.Namespace)
+		}
+		dir = filepath.Join(dir, cfg.Namespace)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	d := &Disk{
+		dir:   dir,
+		index: newIndex(cfg),
+	}
+
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// load adds the valid files in the directory to the index and removes the invalid ones.
+func (d *Disk) load() error {
+	dirEntries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	type loaded struct {
+		key, version, revision string
+		size                   int64
+		stored                 time.Time
+	}
+
+	var files []loaded
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		path := filepath.Join(d.dir, name)
+
+		// Leftovers from writes that were interrupted
+		if strings.HasPrefix(name, diskTempPrefix) {
+			_ = os.Remove(path)
+			continue
+		}
+
+		if dirEntry.IsDir() || !strings.HasSuffix(name, diskFileSuffix) {
+			continue
+		}
+
+		key, revision, data, err := readDiskFile(path)
+		if err != nil || name != diskFileName(key) {
+			slog.Warn("removing invalid cache file", "file", name, "error", err)
+			_ = os.Remove(path)
+			continue
+		}
+
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+
+		version, _, _ := strings.Cut(key, "/")
+		files = append(files, loaded{
+			key:      key,
+			version:  version,
+			revision: revision,
+			size:     int64(len(data)),
+			stored:   info.ModTime(),
+		})
+	}
+
+	// Add the oldest first so that the newest end up as the most recently used
+	slices.SortFunc(files, func(a, b loaded) int {
+		return a.stored.Compare(b.stored)
+	})
+
+	for _, f := range files {
+		d.removeFiles(d.index.add(f.key, f.version, f.revision, f.size, f.stored))
+	}
+
+	slog.Info("loaded disk cache", "entries", d.index.lru.Len(), "bytes", d.index.bytes)
+
+	return nil
+}
+
+func (d *Disk) Get(version, revision, file string) ([]byte, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	key := getCacheKey(version, file)
+	if !d.index.get(key, revision) {
+		return nil, nil
+	}
+
+	return d.read(key)
+}
+
+func (d *Disk) GetStale(version, file string) ([]byte, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	key := getCacheKey(version, file)
+	if !d.index.getStale(key) {
+		return nil, nil
+	}
+
+	return d.read(key)
+}
+
+// read reads the file of a key in the index, the lock must be held.
+func (d *Disk) read(key string) ([]byte, error) {
+	storedKey, _, data, err := readDiskFile(d.path(key))
+	if err != nil || storedKey != key {
+		// The file was removed or corrupted behind our back, treat it as a miss
+		slog.Warn("removing invalid cache file", "key", key, "error", err)
+		d.removeKey(key)
+		return nil, nil
+	}
+
+	return data, nil
+}
+
+func (d *Disk) Set(version, revision, file string, data []byte) error {
+	// A file that can never fit is not cached at all
+	if !d.index.fits(int64(len(data))) {
+		return nil
+	}
+
+	key := getCacheKey(version, file)
+	if strings.Contains(key, "\n") || strings.Contains(revision, "\n") {
+		return fmt.Errorf("invalid cache key: %q", key)
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if err := writeDiskFile(d.dir, d.path(key), key, revision, data); err != nil {
+		return err
+	}
+
+	d.removeFiles(d.index.add(key, version, revision, int64(len(data)), time.Now()))
+
+	return nil
+}
+
+func (d *Disk) Has(version, revision, file string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.index.has(getCacheKey(version, file), revision)
+}
+
+func (d *Disk) RemoveVersion(version string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.removeFiles(d.index.removeVersion(version))
+}
+
+// removeKey removes a single entry from the cache, the lock must be held.
+func (d *Disk) removeKey(key string) {
+	if el, ok := d.index.entries[key]; ok {
+		d.removeFiles([]*entry{d.index.remove(el)})
+	}
+}
+
+func (d *Disk) Stats() Stats {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.index.currentStats()
+}
+
+func (d *Disk) removeFiles(entries []*entry) {
+	for _, e := range entries {
+		if err := os.Remove(d.path(e.key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("failed to remove cache file", "key", e.key, "error", err)
+		}
+	}
+}
+
+func (d *Disk) path(key string) string {
+	return filepath.Join(d.dir, diskFileName(key))
+}
+
+// diskFileName returns the name of the file storing a key.
+// The key is hashed since versions and files can contain characters that are not allowed in file names.
+func diskFileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + diskFileSuffix
+}
+
+// writeDiskFile writes the file atomically by writing to a temporary file and renaming it.
+func writeDiskFile(dir, path, key, revision string, data []byte) error {
+	f, err := os.CreateTemp(dir, diskTempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	sum := sha256.Sum256(data)
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "%s\n%s\n%s\n%s\n", diskFormatHeader, key, revision, hex.EncodeToString(sum[:]))
+	w.Write(data)
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync cache file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close cache file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename cache file: %w", err)
+	}
+
+	return nil
+}
+
+// readDiskFile reads a cached file and validates its checksum.
+func readDiskFile(path string) (key, revision string, data []byte, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	r := bufio.NewReader(bytes.NewReader(b))
+
+	header, err := readLine(r)
+	if err != nil || header != diskFormatHeader {
+		return "", "", nil, fmt.Errorf("unknown format")
+	}
+
+	key, err = readLine(r)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("missing key")
+	}
+
+	revision, err = readLine(r)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("missing revision")
+	}
+
+	checksum, err := readLine(r)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("missing checksum")
+	}
+
+	data, err = io.ReadAll(r)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != checksum {
+		return "", "", nil, fmt.Errorf("checksum mismatch")
+	}
+
+	return key, revision, data, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(line, "\n"), nil
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"testing"
+)
+
+func TestDiskRevisionAfterReload(t *testing.T) {
+	cfg := &Config{Dir: t.TempDir()}
+
+	d, err := NewDisk(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set("main", "a1", "api", []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The version moved while the server was stopped
+	d, err = NewDisk(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		revision string
+		want     string
+	}{
+		{name: "same revision", revision: "a1", want: "old"},
+		{name: "other revision", revision: "b2", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := d.Get("main", tt.revision, "api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got %q, want %q", data, tt.want)
+			}
+
+			if has := d.Has("main", tt.revision, "api"); has != (tt.want != "") {
+				t.Errorf("got has %v", has)
+			}
+		})
+	}
+
+	// The outdated copy is still served if the provider fails
+	stale, err := d.GetStale("main", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(stale) != "old" {
+		t.Errorf("got stale %q, want %q", stale, "old")
+	}
+}
+
+func TestDiskNamespaces(t *testing.T) {
+	dir := t.TempDir()
+
+	a, err := NewDisk(&Config{Dir: dir, Namespace: "a", MaxEntries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewDisk(&Config{Dir: dir, Namespace: "b", MaxEntries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Set("v1", "r", "api", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Set("v1", "r", "api", []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reloading one project must neither see nor evict the files of the other
+	b, err = NewDisk(&Config{Dir: dir, Namespace: "b", MaxEntries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, c := range map[string]*Disk{"a": a, "b": b} {
+		data, err := c.Get("v1", "r", "api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != name {
+			t.Errorf("got %q from project %s", data, name)
+		}
+	}
+}
+
+func TestDiskInvalidNamespace(t *testing.T) {
+	for _, namespace := range []string{"..", "a/b", `a\b`} {
+		if _, err := NewDisk(&Config{Dir: t.TempDir(), Namespace: namespace}); err == nil {
+			t.Errorf("expected an error for namespace %q", namespace)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+)
+
+// index keeps track of the entries of a cache and decides which of them to evict
+// when the cache grows beyond its limits. It does not hold the data of the entries.
+type index struct {
+	cfg *Config
+
+	// The entries keyed by version and file, pointing into lru
+	entries map[string]*list.Element
+	// The entries with the most recently used first
+	lru *list.List
+	// The total size of the entries
+	bytes int64
+
+	stats Stats
+}
+
+type entry struct {
+	key     string
+	version string
+	// The revision of the version the entry was downloaded at
+	revision string
+	size     int64
+	// When the entry expires, zero if it never does
+	expires time.Time
+}
+
+func (e *entry) expired() bool {
+	return !e.expires.IsZero() && time.Now().After(e.expires)
+}
+
+// valid reports if the entry can be used for the revision.
+func (e *entry) valid(revision string) bool {
+	return e.revision == revision && !e.expired()
+}
+
+func newIndex(cfg *Config) *index {
+	return &index{
+		cfg:     cfg,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get marks the entry as used and reports if it exists at the revision and has not expired.
+// Expired entries and entries of other revisions are kept as stale copies until they are replaced or evicted.
+func (i *index) get(key, revision string) bool {
+	el, ok := i.entries[key]
+	if !ok || !el.Value.(*entry).valid(revision) {
+		i.stats.Misses++
+		return false
+	}
+
+	i.lru.MoveToFront(el)
+	i.stats.Hits++
+	return true
+}
+
+// getStale marks the entry as used and reports if it exists, even if it has expired.
+func (i *index) getStale(key string) bool {
+	el, ok := i.entries[key]
+	if !ok {
+		return false
+	}
+
+	i.lru.MoveToFront(el)
+	i.stats.StaleHits++
+	return true
+}
+
+// has reports if the entry exists at the revision and has not expired without marking it as used.
+func (i *index) has(key, revision string) bool {
+	el, ok := i.entries[key]
+	if !ok {
+		return false
+	}
+
+	return el.Value.(*entry).valid(revision)
+}
+
+// fits reports if an entry of the size can ever be stored.
+func (i *index) fits(size int64) bool {
+	return i.cfg.MaxBytes <= 0 || size <= i.cfg.MaxBytes
+}
+
+// add adds or replaces an entry as the most recently used one, stored at the given time.
+// It returns the entries that were evicted to make room for it.
+func (i *index) add(key, version, revision string, size int64, stored time.Time) (evicted []*entry) {
+	if el, ok := i.entries[key]; ok {
+		i.remove(el)
+	}
+
+	e := &entry{
+		key:      key,
+		version:  version,
+		revision: revision,
+		size:     size,
+	}
+	if i.cfg.TTL > 0 {
+		e.expires = stored.Add(i.cfg.TTL)
+	}
+
+	i.entries[key] = i.lru.PushFront(e)
+	i.bytes += size
+
+	for i.overLimit() {
+		el := i.lru.Back()
+		if el == nil {
+			break
+		}
+
+		evicted = append(evicted, i.remove(el))
+		i.stats.Evictions++
+	}
+
+	return evicted
+}
+
+// removeVersion removes all entries of a version and returns them.
+func (i *index) removeVersion(version string) (removed []*entry) {
+	for el := i.lru.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*entry).version == version {
+			removed = append(removed, i.remove(el))
+		}
+		el = next
+	}
+
+	return removed
+}
+
+func (i *index) overLimit() bool {
+	if i.cfg.MaxBytes > 0 && i.bytes > i.cfg.MaxBytes {
+		return true
+	}
+
+	return i.cfg.MaxEntries > 0 && i.lru.Len() > i.cfg.MaxEntries
+}
+
+func (i *index) remove(el *list.Element) *entry {
+	e := i.lru.Remove(el).(*entry)
+	delete(i.entries, e.key)
+	i.bytes -= e.size
+	return e
+}
+
+func (i *index) currentStats() Stats {
+	stats := i.stats
+	stats.Entries = i.lru.Len()
+	stats.Bytes = i.bytes
+
+	return stats
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Memory is an in-memory cache that evicts the least recently used files
+// when it grows beyond its limits. It is safe for concurrent use.
+type Memory struct {
+	// Every access modifies the order of the entries so even reads need an exclusive lock
+	lock sync.Mutex
+
+	index *index
+	data  map[string][]byte
+}
+
+func NewMemory(cfg *Config) *Memory {
+	return &Memory{
+		index: newIndex(cfg),
+		data:  make(map[string][]byte),
+	}
+}
+
+func (p *Memory) Get(version, revision, file string) ([]byte, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	key := getCacheKey(version, file)
+
+	if !p.index.get(key, revision) {
+		return nil, nil
+	}
+
+	return p.data[key], nil
+}
+
+func (p *Memory) GetStale(version, file string) ([]byte, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	key := getCacheKey(version, file)
+
+	if !p.index.getStale(key) {
+		return nil, nil
+	}
+
+	return p.data[key], nil
+}
+
+func (p *Memory) Set(version, revision, file string, data []byte) error {
+	// A file that can never fit is not cached at all
+	if !p.index.fits(int64(len(data))) {
+		return nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	key := getCacheKey(version, file)
+	p.data[key] = data
+
+	for _, e := range p.index.add(key, version, revision, int64(len(data)), time.Now()) {
+		delete(p.data, e.key)
+	}
+
+	return nil
+}
+
+func (p *Memory) Has(version, revision, file string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.index.has(getCacheKey(version, file), revision)
+}
+
+func (p *Memory) RemoveVersion(version string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, e := range p.index.removeVersion(version) {
+		delete(p.data, e.key)
+	}
+}
+
+func (p *Memory) Stats() Stats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.index.currentStats()
+}
@@ -10,28 +10,17 @@ import (
 type Config struct {
 	LogLevel string `yaml:"log_level"`
 
-	Provider struct {
-		Github *struct {
-			Owner      string `yaml:"owner"`
-			Repo       string `yaml:"repo"`
-			PathPrefix string `yaml:"path_prefix"`
-			FileSuffix string `yaml:"file_suffix"`
-			MaxTags    int    `yaml:"max_tags"`
-			AuthToken  string `yaml:"auth_token"`
-		} `yaml:"github"`
-		File *struct {
-			FilePath string `yaml:"file_path"`
-		} `yaml:"file"`
-	} `yaml:"provider"`
-
-	Server struct {
-		PollInterval string `yaml:"poll_interval"`
-		Proxy        bool   `yaml:"proxy"`
-	} `yaml:"server"`
+	Provider ProviderConfig `yaml:"provider"`
+	Server   ServerConfig   `yaml:"server"`
+
+	// Projects to serve instead of the single provider and server above.
+	// Each project has its own provider and server and is served under its name.
+	Projects []ProjectConfig `yaml:"projects"`
 
 	App struct {
-		Address    string `yaml:"address"`
-		PathPrefix string `yaml:"path_prefix"`
+		Address       string `yaml:"address"`
+		PathPrefix    string `yaml:"path_prefix"`
+		WebhookSecret string `yaml:"webhook_secret"`
 	} `yaml:"app"`
 
 	Design struct {
@@ -42,6 +31,124 @@ type Config struct {
 	} `yaml:"design"`
 }
 
+type ProjectConfig struct {
+	// The name of the project, used in the urls
+	Name     string         `yaml:"name"`
+	Provider ProviderConfig `yaml:"provider"`
+	Server   ServerConfig   `yaml:"server"`
+}
+
+type ProviderConfig struct {
+	Github *struct {
+		Owner      string `yaml:"owner"`
+		Repo       string `yaml:"repo"`
+		PathPrefix string `yaml:"path_prefix"`
+		FileSuffix string `yaml:"file_suffix"`
+		MaxTags    int    `yaml:"max_tags"`
+		AuthToken  string `yaml:"auth_token"`
+
+		BaseURL   string `yaml:"base_url"`
+		UploadURL string `yaml:"upload_url"`
+		RawURL    string `yaml:"raw_url"`
+		CABundle  string `yaml:"ca_bundle"`
+
+		App *struct {
+			AppID          int64  `yaml:"app_id"`
+			InstallationID int64  `yaml:"installation_id"`
+			PrivateKey     string `yaml:"private_key"`
+			PrivateKeyFile string `yaml:"private_key_file"`
+		} `yaml:"app"`
+
+		TagOrder    string `yaml:"tag_order"`
+		UseReleases bool   `yaml:"use_releases"`
+
+		SkipTags        bool     `yaml:"skip_tags"`
+		Branches        []string `yaml:"branches"`
+		MaxBranches     int      `yaml:"max_branches"`
+		PullRequests    bool     `yaml:"pull_requests"`
+		MaxPullRequests int      `yaml:"max_pull_requests"`
+	} `yaml:"github"`
+	Gitlab *struct {
+		BaseURL    string `yaml:"base_url"`
+		Project    string `yaml:"project"`
+		PathPrefix string `yaml:"path_prefix"`
+		FileSuffix string `yaml:"file_suffix"`
+		MaxTags    int    `yaml:"max_tags"`
+		AuthToken  string `yaml:"auth_token"`
+	} `yaml:"gitlab"`
+	Gitea *struct {
+		BaseURL    string `yaml:"base_url"`
+		Owner      string `yaml:"owner"`
+		Repo       string `yaml:"repo"`
+		PathPrefix string `yaml:"path_prefix"`
+		FileSuffix string `yaml:"file_suffix"`
+		MaxTags    int    `yaml:"max_tags"`
+		AuthToken  string `yaml:"auth_token"`
+	} `yaml:"gitea"`
+	Git *struct {
+		RepoPath      string `yaml:"repo_path"`
+		PathPrefix    string `yaml:"path_prefix"`
+		FileSuffix    string `yaml:"file_suffix"`
+		MaxTags       int    `yaml:"max_tags"`
+		Remote        string `yaml:"remote"`
+		FetchInterval string `yaml:"fetch_interval"`
+	} `yaml:"git"`
+	File *struct {
+		FilePath      string `yaml:"file_path"`
+		FileSuffix    string `yaml:"file_suffix"`
+		Versioned     bool   `yaml:"versioned"`
+		SingleVersion string `yaml:"single_version"`
+	} `yaml:"file"`
+	S3 *struct {
+		Endpoint        string `yaml:"endpoint"`
+		Region          string `yaml:"region"`
+		Bucket          string `yaml:"bucket"`
+		Prefix          string `yaml:"prefix"`
+		FileSuffix      string `yaml:"file_suffix"`
+		AccessKeyID     string `yaml:"access_key_id"`
+		SecretAccessKey string `yaml:"secret_access_key"`
+		PathStyle       bool   `yaml:"path_style"`
+		PresignExpiry   string `yaml:"presign_expiry"`
+	} `yaml:"s3"`
+	Manifest *struct {
+		ManifestURL string `yaml:"manifest_url"`
+		BearerToken string `yaml:"bearer_token"`
+		Username    string `yaml:"username"`
+		Password    string `yaml:"password"`
+		// Send the credentials with the files on other hosts than the manifest too
+		AuthAllOrigins bool   `yaml:"auth_all_origins"`
+		CABundle       string `yaml:"ca_bundle"`
+	} `yaml:"manifest"`
+	OCI *struct {
+		Registry   string `yaml:"registry"`
+		Repository string `yaml:"repository"`
+		FileSuffix string `yaml:"file_suffix"`
+		MaxTags    int    `yaml:"max_tags"`
+		Username   string `yaml:"username"`
+		Password   string `yaml:"password"`
+		CABundle   string `yaml:"ca_bundle"`
+	} `yaml:"oci"`
+}
+
+type ServerConfig struct {
+	PollInterval string `yaml:"poll_interval"`
+	Proxy        bool   `yaml:"proxy"`
+	Cache        struct {
+		MaxBytes   int64  `yaml:"max_bytes"`
+		MaxEntries int    `yaml:"max_entries"`
+		TTL        string `yaml:"ttl"`
+		Dir        string `yaml:"dir"`
+	} `yaml:"cache"`
+	Prefetch            bool `yaml:"prefetch"`
+	PrefetchConcurrency int  `yaml:"prefetch_concurrency"`
+	Watch               bool `yaml:"watch"`
+
+	Include         string `yaml:"include"`
+	Exclude         string `yaml:"exclude"`
+	HidePrereleases bool   `yaml:"hide_prereleases"`
+	KeepPatches     int    `yaml:"keep_patches"`
+}
+
 func loadConfig() (*Config, error) {
 	content, err := os.ReadFile("./config.yml")
 	if err != nil {
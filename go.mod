@@ -3,9 +3,11 @@ module github.com/theleeeo/docs-server
 go 1.25
 
 require (
+	github.com/andybalholm/brotli v1.1.1
 	github.com/fatih/color v1.16.0
 	github.com/google/go-github/v58 v58.0.0
 	github.com/theleeeo/leolog v0.0.0-20240201202331-5ee228d0f1da
+	golang.org/x/sys v0.15.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -13,5 +15,4 @@ require (
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/sys v0.15.0 // indirect
 )
@@ -4,7 +4,6 @@ package main
 // - Handle a 404 better
 // - Tests, ofc
 // - Admin endpoints?
-// - File provider
 // - STD lib instead of fiber
 // - Do not crach on an api error. Log and move on
 // /TODO:
@@ -22,6 +21,7 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/theleeeo/docs-server/app"
+	"github.com/theleeeo/docs-server/cache"
 	"github.com/theleeeo/docs-server/provider"
 	"github.com/theleeeo/docs-server/server"
 	"github.com/theleeeo/leolog"
@@ -76,26 +76,21 @@ func main() {
 	logger := slog.New(leolog.NewHandler(&slog.HandlerOptions{Level: logLevel}))
 	slog.SetDefault(logger)
 
-	providerClient, err := setupProvider(cfg)
+	projects, err := setupProjects(cfg)
 	if err != nil {
-		color.Red("failed to setup provider: %s", err)
-		return
-	}
-
-	s, err := setupServer(cfg, providerClient)
-	if err != nil {
-		color.Red("failed to setup server: %s", err)
+		color.Red("failed to setup projects: %s", err)
 		return
 	}
 
 	app, err := app.New(&app.Config{
-		Address:     cfg.App.Address,
-		HeaderTitle: cfg.Design.HeaderTitle,
-		HeaderImage: cfg.Design.HeaderImage,
-		HeaderColor: cfg.Design.HeaderColor,
-		Favicon:     cfg.Design.Favicon,
-		PathPrefix:  cfg.App.PathPrefix,
-	}, s)
+		Address:       cfg.App.Address,
+		HeaderTitle:   cfg.Design.HeaderTitle,
+		HeaderImage:   cfg.Design.HeaderImage,
+		HeaderColor:   cfg.Design.HeaderColor,
+		Favicon:       cfg.Design.Favicon,
+		PathPrefix:    cfg.App.PathPrefix,
+		WebhookSecret: cfg.App.WebhookSecret,
+	}, projects)
 	if err != nil {
 		color.Red("failed to create app: %s", err)
 		return
@@ -118,12 +113,14 @@ func main() {
 		}
 	}()
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	for _, p := range projects {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 
-		s.Run(ctx)
-	}()
+			p.Server.Run(ctx)
+		}()
+	}
 
 	select {
 	case <-termChan:
@@ -144,31 +141,205 @@ func main() {
 	wg.Wait()
 }
 
-func setupProvider(cfg *Config) (p server.Provider, err error) {
+func setupProjects(cfg *Config) ([]*app.Project, error) {
+	// Without any projects the top level provider is served as a single unnamed project
+	if len(cfg.Projects) == 0 {
+		s, err := setupProject("", &cfg.Provider, &cfg.Server)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*app.Project{{Server: s}}, nil
+	}
+
+	var projects []*app.Project
+	for _, pc := range cfg.Projects {
+		if pc.Name == "" {
+			return nil, fmt.Errorf("project name cannot be empty")
+		}
+
+		s, err := setupProject(pc.Name, &pc.Provider, &pc.Server)
+		if err != nil {
+			return nil, fmt.Errorf("project %s: %w", pc.Name, err)
+		}
+
+		projects = append(projects, &app.Project{
+			Name:   pc.Name,
+			Server: s,
+		})
+	}
+
+	return projects, nil
+}
+
+func setupProject(name string, providerCfg *ProviderConfig, serverCfg *ServerConfig) (*server.Server, error) {
+	p, err := setupProvider(providerCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup provider: %w", err)
+	}
+
+	// Links to files of a private repository would not work in the browser
+	proxy := serverCfg.Proxy || server.RequiresProxy(context.Background(), p)
+
+	s, err := setupServer(name, serverCfg, proxy, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup server: %w", err)
+	}
+
+	return s, nil
+}
+
+func setupProvider(cfg *ProviderConfig) (p server.Provider, err error) {
 	// This pattern is possible extensibility, even if it's not used atm.
-	if cfg.Provider.Github != nil {
+	if cfg.Github != nil {
 		ghConfig := &provider.GithubConfig{
-			Owner:      cfg.Provider.Github.Owner,
-			Repo:       cfg.Provider.Github.Repo,
-			PathPrefix: cfg.Provider.Github.PathPrefix,
-			FileSuffix: cfg.Provider.Github.FileSuffix,
-			MaxTags:    cfg.Provider.Github.MaxTags,
-			AuthToken:  cfg.Provider.Github.AuthToken,
+			Owner:      cfg.Github.Owner,
+			Repo:       cfg.Github.Repo,
+			PathPrefix: cfg.Github.PathPrefix,
+			FileSuffix: cfg.Github.FileSuffix,
+			MaxTags:    cfg.Github.MaxTags,
+			AuthToken:  cfg.Github.AuthToken,
+
+			BaseURL:   cfg.Github.BaseURL,
+			UploadURL: cfg.Github.UploadURL,
+			RawURL:    cfg.Github.RawURL,
+			CABundle:  cfg.Github.CABundle,
+
+			TagOrder:    cfg.Github.TagOrder,
+			UseReleases: cfg.Github.UseReleases,
+
+			SkipTags:        cfg.Github.SkipTags,
+			Branches:        cfg.Github.Branches,
+			MaxBranches:     cfg.Github.MaxBranches,
+			PullRequests:    cfg.Github.PullRequests,
+			MaxPullRequests: cfg.Github.MaxPullRequests,
+		}
+
+		if app := cfg.Github.App; app != nil {
+			ghConfig.App = &provider.GithubAppConfig{
+				AppID:          app.AppID,
+				InstallationID: app.InstallationID,
+				PrivateKey:     app.PrivateKey,
+				PrivateKeyFile: app.PrivateKeyFile,
+			}
 		}
 
 		p, err = provider.NewGithub(ghConfig)
 		if err != nil {
 			return nil, err
 		}
-	} else if cfg.Provider.File != nil {
+	} else if cfg.Gitlab != nil {
+		glConfig := &provider.GitlabConfig{
+			BaseURL:    cfg.Gitlab.BaseURL,
+			Project:    cfg.Gitlab.Project,
+			PathPrefix: cfg.Gitlab.PathPrefix,
+			FileSuffix: cfg.Gitlab.FileSuffix,
+			MaxTags:    cfg.Gitlab.MaxTags,
+			AuthToken:  cfg.Gitlab.AuthToken,
+		}
+
+		p, err = provider.NewGitlab(glConfig)
+		if err != nil {
+			return nil, err
+		}
+	} else if cfg.Gitea != nil {
+		giteaConfig := &provider.GiteaConfig{
+			BaseURL:    cfg.Gitea.BaseURL,
+			Owner:      cfg.Gitea.Owner,
+			Repo:       cfg.Gitea.Repo,
+			PathPrefix: cfg.Gitea.PathPrefix,
+			FileSuffix: cfg.Gitea.FileSuffix,
+			MaxTags:    cfg.Gitea.MaxTags,
+			AuthToken:  cfg.Gitea.AuthToken,
+		}
+
+		p, err = provider.NewGitea(giteaConfig)
+		if err != nil {
+			return nil, err
+		}
+	} else if cfg.Git != nil {
+		fetchInterval, err := parseInterval(cfg.Git.FetchInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse fetch interval: %w", err)
+		}
+
+		gitConfig := &provider.GitConfig{
+			RepoPath:      cfg.Git.RepoPath,
+			PathPrefix:    cfg.Git.PathPrefix,
+			FileSuffix:    cfg.Git.FileSuffix,
+			MaxTags:       cfg.Git.MaxTags,
+			Remote:        cfg.Git.Remote,
+			FetchInterval: fetchInterval,
+		}
+
+		p, err = provider.NewGit(gitConfig)
+		if err != nil {
+			return nil, err
+		}
+	} else if cfg.File != nil {
 		fileConfig := &provider.FileConfig{
-			FilePath: cfg.Provider.File.FilePath,
+			FilePath:      cfg.File.FilePath,
+			FileSuffix:    cfg.File.FileSuffix,
+			Versioned:     cfg.File.Versioned,
+			SingleVersion: cfg.File.SingleVersion,
 		}
 
 		p, err = provider.NewFileProvider(fileConfig)
 		if err != nil {
 			return nil, err
 		}
+	} else if cfg.S3 != nil {
+		presignExpiry, err := parseInterval(cfg.S3.PresignExpiry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse presign expiry: %w", err)
+		}
+
+		s3Config := &provider.S3Config{
+			Endpoint:        cfg.S3.Endpoint,
+			Region:          cfg.S3.Region,
+			Bucket:          cfg.S3.Bucket,
+			Prefix:          cfg.S3.Prefix,
+			FileSuffix:      cfg.S3.FileSuffix,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+			PathStyle:       cfg.S3.PathStyle,
+			PresignExpiry:   presignExpiry,
+		}
+
+		p, err = provider.NewS3(s3Config)
+		if err != nil {
+			return nil, err
+		}
+	} else if cfg.Manifest != nil {
+		manifestConfig := &provider.ManifestConfig{
+			ManifestURL: cfg.Manifest.ManifestURL,
+			BearerToken: cfg.Manifest.BearerToken,
+			Username:    cfg.Manifest.Username,
+			Password:    cfg.Manifest.Password,
+			CABundle:    cfg.Manifest.CABundle,
+
+			AuthAllOrigins: cfg.Manifest.AuthAllOrigins,
+		}
+
+		p, err = provider.NewManifest(manifestConfig)
+		if err != nil {
+			return nil, err
+		}
+	} else if cfg.OCI != nil {
+		ociConfig := &provider.OCIConfig{
+			Registry:   cfg.OCI.Registry,
+			Repository: cfg.OCI.Repository,
+			FileSuffix: cfg.OCI.FileSuffix,
+			MaxTags:    cfg.OCI.MaxTags,
+			Username:   cfg.OCI.Username,
+			Password:   cfg.OCI.Password,
+			CABundle:   cfg.OCI.CABundle,
+		}
+
+		p, err = provider.NewOCI(ociConfig)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if p == nil {
@@ -178,15 +349,36 @@ func setupProvider(cfg *Config) (p server.Provider, err error) {
 	return p, nil
 }
 
-func setupServer(cfg *Config, p server.Provider) (s *server.Server, err error) {
-	interval, err := parseInterval(cfg.Server.PollInterval)
+// setupServer creates the server of a project, the name keeps its cached files apart from the other projects.
+func setupServer(name string, cfg *ServerConfig, proxy bool, p server.Provider) (s *server.Server, err error) {
+	interval, err := parseInterval(cfg.PollInterval)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse poll interval: %w", err)
 	}
 
+	cacheTTL, err := parseInterval(cfg.Cache.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cache ttl: %w", err)
+	}
+
 	serverConfig := &server.Config{
 		PollInterval: interval,
-		Proxy:        cfg.Server.Proxy,
+		Proxy:        proxy,
+		Cache: cache.Config{
+			MaxBytes:   cfg.Cache.MaxBytes,
+			MaxEntries: cfg.Cache.MaxEntries,
+			TTL:        cacheTTL,
+			Dir:        cfg.Cache.Dir,
+			Namespace:  name,
+		},
+		Prefetch:            cfg.Prefetch,
+		PrefetchConcurrency: cfg.PrefetchConcurrency,
+		Watch:               cfg.Watch,
+
+		Include:         cfg.Include,
+		Exclude:         cfg.Exclude,
+		HidePrereleases: cfg.HidePrereleases,
+		KeepPatches:     cfg.KeepPatches,
 	}
 
 	s, err = server.New(serverConfig, p)
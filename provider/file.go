@@ -2,9 +2,17 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"io/fs"
+	"log/slog"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 type FileProvider struct {
@@ -12,8 +20,19 @@ type FileProvider struct {
 	cfg  *FileConfig
 }
 
+const (
+	// The name of the version of the flat layout if no other name is configured
+	defaultFileVersion = "file"
+)
+
 type FileConfig struct {
-	FilePath string
+	// The directory containing the files, or one subdirectory per version if Versioned is set
+	FilePath   string
+	FileSuffix string
+	// Treat each subdirectory of FilePath as a version
+	Versioned bool
+	// The name of the single version of the flat layout, defaults to "file"
+	SingleVersion string
 }
 
 func NewFileProvider(cfg *FileConfig) (*FileProvider, error) {
@@ -21,36 +40,163 @@ func NewFileProvider(cfg *FileConfig) (*FileProvider, error) {
 		return nil, fmt.Errorf("file path cannot be empty")
 	}
 
+	info, err := os.Stat(cfg.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file path: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("file path must be a directory")
+	}
+
+	if cfg.Versioned && cfg.SingleVersion != "" {
+		return nil, fmt.Errorf("single version cannot be set when using versioned directories")
+	}
+
+	// The flat layout is used unless versioned directories are asked for
+	if !cfg.Versioned && cfg.SingleVersion == "" {
+		cfg.SingleVersion = defaultFileVersion
+	}
+
 	return &FileProvider{
 		path: cfg.FilePath,
 		cfg:  cfg,
 	}, nil
 }
 
-// Get the names of all tags in the repository
-func (p *FileProvider) ListVersions(ctx context.Context) ([]string, error) {
-	return []string{"file"}, nil
+// Get the names of all version directories
+func (p *FileProvider) ListVersions(ctx context.Context) ([]Version, error) {
+	var names []string
+	if p.cfg.SingleVersion != "" {
+		names = []string{p.cfg.SingleVersion}
+	} else {
+		entries, err := os.ReadDir(p.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read root directory: %w", err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+
+	var versions []Version
+	for _, name := range names {
+		revision, err := p.revision(name)
+		if err != nil {
+			return nil, err
+		}
+
+		// The files on disk can be edited at any time
+		versions = append(versions, Version{Name: name, Revision: revision, Moving: true})
+	}
+
+	return versions, nil
+}
+
+// revision returns a hash of the names, sizes and modification times of the files in a version.
+func (p *FileProvider) revision(version string) (string, error) {
+	dir, err := p.versionDir(version)
+	if err != nil {
+		return "", err
+	}
+
+	h := fnv.New64a()
+	err = filepath.WalkDir(filepath.Join(p.path, dir), func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if name != filepath.Join(p.path, dir) && strings.HasPrefix(entry.Name(), ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00", name, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read version directory: %w", err)
+	}
+
+	return strconv.FormatUint(h.Sum64(), 16), nil
 }
 
 func (p *FileProvider) ListFiles(ctx context.Context, version string) ([]string, error) {
-	entries, err := os.ReadDir(p.path)
+	root, err := os.OpenRoot(p.path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read root directory: %w", err)
+		return nil, fmt.Errorf("failed to open root directory: %w", err)
+	}
+	defer root.Close()
+
+	dir, err := p.versionDir(version)
+	if err != nil {
+		return nil, err
 	}
 
 	var files []string
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			files = append(files, entry.Name())
+	err = fs.WalkDir(root.FS(), dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
 		}
+
+		if entry.IsDir() {
+			// Skip hidden directories such as .git
+			if name != dir && strings.HasPrefix(entry.Name(), ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		f := name
+		if dir != "." {
+			f = strings.TrimPrefix(name, dir+"/")
+		}
+
+		// If the file does not end with the suffix, skip it
+		f, ok := strings.CutSuffix(f, p.cfg.FileSuffix)
+		if !ok {
+			slog.Warn("file does not end with the suffix, skipping", "file", f, "version", version, "suffix", p.cfg.FileSuffix)
+			return nil
+		}
+
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: version=%s", ErrNotFound, version)
+		}
+		return nil, fmt.Errorf("failed to read version directory: %w", err)
 	}
 
 	return files, nil
 }
 
 func (p *FileProvider) DownloadFile(ctx context.Context, version, file string) ([]byte, error) {
-	f, err := os.Open(p.path + "/" + file)
+	dir, err := p.versionDir(version)
 	if err != nil {
+		return nil, err
+	}
+
+	if !fs.ValidPath(file) {
+		return nil, fmt.Errorf("%w: file=%s", ErrNotFound, file)
+	}
+
+	// Opening the file in the root prevents symlinks from escaping the directory
+	f, err := os.OpenInRoot(p.path, path.Join(dir, file+p.cfg.FileSuffix))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: file=%s", ErrNotFound, file)
+		}
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer f.Close()
@@ -63,7 +209,24 @@ func (p *FileProvider) DownloadFile(ctx context.Context, version, file string) (
 	return data, nil
 }
 
-// TODO: Handle
+// GetPath returns an empty path since the files are not reachable outside of the server.
+// They are instead served by the server itself.
 func (p *FileProvider) GetPath(version, file string) string {
-	panic("not implemented")
+	return ""
+}
+
+// versionDir returns the directory of a version relative to the root directory.
+func (p *FileProvider) versionDir(version string) (string, error) {
+	if p.cfg.SingleVersion != "" {
+		if version != p.cfg.SingleVersion {
+			return "", fmt.Errorf("%w: version=%s", ErrNotFound, version)
+		}
+		return ".", nil
+	}
+
+	if version == "" || strings.ContainsAny(version, `/\`) || version == "." || version == ".." {
+		return "", fmt.Errorf("%w: version=%s", ErrNotFound, version)
+	}
+
+	return version, nil
 }
@@ -0,0 +1,91 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFileProviderLayouts(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"root.json", "v1/a.json", "v2/b.json"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		cfg          FileConfig
+		wantVersions []string
+		wantFiles    map[string][]string
+	}{
+		{
+			name:         "flat by default",
+			cfg:          FileConfig{FilePath: dir, FileSuffix: ".json"},
+			wantVersions: []string{"file"},
+			wantFiles:    map[string][]string{"file": {"root", "v1/a", "v2/b"}},
+		},
+		{
+			name:         "flat with a name",
+			cfg:          FileConfig{FilePath: dir, FileSuffix: ".json", SingleVersion: "latest"},
+			wantVersions: []string{"latest"},
+			wantFiles:    map[string][]string{"latest": {"root", "v1/a", "v2/b"}},
+		},
+		{
+			name:         "versioned directories",
+			cfg:          FileConfig{FilePath: dir, FileSuffix: ".json", Versioned: true},
+			wantVersions: []string{"v1", "v2"},
+			wantFiles:    map[string][]string{"v1": {"a"}, "v2": {"b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewFileProvider(&tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			versions, err := p.ListVersions(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, v := range versions {
+				names = append(names, v.Name)
+			}
+			slices.Sort(names)
+
+			if !slices.Equal(names, tt.wantVersions) {
+				t.Errorf("got versions %v, want %v", names, tt.wantVersions)
+			}
+
+			for version, want := range tt.wantFiles {
+				files, err := p.ListFiles(context.Background(), version)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				slices.Sort(files)
+				if !slices.Equal(files, want) {
+					t.Errorf("got files %v of %s, want %v", files, version, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileProviderConflictingLayout(t *testing.T) {
+	_, err := NewFileProvider(&FileConfig{FilePath: t.TempDir(), Versioned: true, SingleVersion: "latest"})
+	if err == nil {
+		t.Error("expected an error when both versioned and single version are set")
+	}
+}
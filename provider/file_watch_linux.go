@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	inotifyMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_CLOSE_WRITE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+)
+
+// Watch watches the directory for changes using inotify and calls changed with the affected version.
+// An empty version means that the list of versions might have changed.
+// It blocks until the context is canceled.
+func (p *FileProvider) Watch(ctx context.Context, changed func(version string)) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+
+	// Wrapping the non-blocking descriptor in a file makes reads use the runtime poller,
+	// which lets the read be interrupted by closing the file.
+	f := os.NewFile(uintptr(fd), "inotify")
+	defer f.Close()
+
+	w := &inotifyWatcher{
+		fd:   fd,
+		root: p.path,
+		dirs: make(map[int]string),
+	}
+
+	if err := w.addRecursive("."); err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read inotify events: %w", err)
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+			offset += unix.SizeofInotifyEvent + int(event.Len)
+
+			dir, ok := w.dirs[int(event.Wd)]
+			if !ok {
+				continue
+			}
+
+			if event.Mask&unix.IN_IGNORED != 0 {
+				delete(w.dirs, int(event.Wd))
+				continue
+			}
+
+			name := filepath.ToSlash(filepath.Join(dir, string(bytes.TrimRight(nameBytes, "\x00"))))
+
+			// New directories have to be watched as well
+			if event.Mask&unix.IN_ISDIR != 0 && event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+				if err := w.addRecursive(name); err != nil {
+					slog.Warn("failed to watch directory", "dir", name, "error", err)
+				}
+			}
+
+			changed(p.versionOf(name, event.Mask&unix.IN_ISDIR != 0))
+		}
+	}
+}
+
+// versionOf returns the version that a path relative to the root directory belongs to.
+func (p *FileProvider) versionOf(name string, isDir bool) string {
+	if p.cfg.SingleVersion != "" {
+		return p.cfg.SingleVersion
+	}
+
+	version, rest, _ := strings.Cut(name, "/")
+	// A directory directly in the root is a version being added or removed
+	if rest == "" && isDir {
+		return ""
+	}
+
+	return version
+}
+
+type inotifyWatcher struct {
+	fd   int
+	root string
+	// The directories being watched relative to the root, keyed by watch descriptor
+	dirs map[int]string
+}
+
+func (w *inotifyWatcher) addRecursive(dir string) error {
+	return filepath.WalkDir(filepath.Join(w.root, dir), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// The directory might have been removed before it could be watched
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil {
+			return err
+		}
+
+		if rel != "." && strings.HasPrefix(entry.Name(), ".") {
+			return fs.SkipDir
+		}
+
+		wd, err := unix.InotifyAddWatch(w.fd, path, inotifyMask)
+		if err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		w.dirs[wd] = filepath.ToSlash(rel)
+
+		return nil
+	})
+}
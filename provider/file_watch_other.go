@@ -0,0 +1,13 @@
+//go:build !linux
+
+package provider
+
+import (
+	"context"
+	"fmt"
+)
+
+// Watch is only supported on linux.
+func (p *FileProvider) Watch(ctx context.Context, changed func(version string)) error {
+	return fmt.Errorf("watching is not supported on this platform")
+}
@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// forgeClient performs the requests of the providers talking to the REST API of a self-hosted forge,
+// such as GitLab or Gitea, tracking the rate limit reported by the API.
+type forgeClient struct {
+	rateLimitTracker
+
+	client *http.Client
+	// The url of the repository in the API, requests are made relative to it
+	apiUrl string
+	// Adds the authentication to a request
+	authorize func(req *http.Request)
+}
+
+func (c *forgeClient) getJSON(ctx context.Context, path string, query url.Values, v any) (*http.Response, error) {
+	resp, err := c.get(ctx, path, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return resp, nil
+}
+
+// get performs a GET request against the repository API.
+// The caller is responsible for closing the body of the response.
+func (c *forgeClient) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := c.apiUrl + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.authorize != nil {
+		c.authorize(req)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit, ok := rateLimitFromHeaders(resp.Header); ok {
+		c.track(limit)
+	}
+
+	if err := handleForgeResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func handleForgeResponse(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, resp.Request.URL.Path)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return rateLimitErrorFromResponse(resp)
+	default:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+// treeFile returns the name of a file of a version from its path in the repository,
+// or false if it is not in the path prefix or does not end with the suffix.
+func treeFile(version, path, pathPrefix, fileSuffix string) (string, bool) {
+	f := path
+	if pathPrefix != "" {
+		var ok bool
+		f, ok = strings.CutPrefix(path, pathPrefix+"/")
+		if !ok {
+			return "", false
+		}
+	}
+
+	// If the file does not end with the suffix, skip it
+	f, ok := strings.CutSuffix(f, fileSuffix)
+	if !ok {
+		slog.Warn("file does not end with the suffix, skipping", "file", f, "version", version, "suffix", fileSuffix)
+		return "", false
+	}
+
+	return f, true
+}
+
+// repoFilePath returns the path of a file relative to the root of the repository.
+func repoFilePath(pathPrefix, file, fileSuffix string) string {
+	if pathPrefix == "" {
+		return fmt.Sprint(file, fileSuffix)
+	}
+	return fmt.Sprint(pathPrefix, "/", file, fileSuffix)
+}
+
+// escapePath escapes each segment of a path, keeping the slashes between them.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
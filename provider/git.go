@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitProvider reads the versions and files straight from a git repository on disk.
+// The repository can be either a bare clone or a regular one, only the refs and objects are used.
+type GitProvider struct {
+	cfg *GitConfig
+
+	fetchLock sync.Mutex
+	lastFetch time.Time
+}
+
+type GitConfig struct {
+	// The path to the repository on disk
+	RepoPath   string
+	PathPrefix string
+	FileSuffix string
+	MaxTags    int
+	// An optional remote to fetch from before listing the versions
+	Remote string
+	// How often to fetch from the remote, it is never done more often than the server polls
+	FetchInterval time.Duration
+}
+
+func NewGit(cfg *GitConfig) (*GitProvider, error) {
+	if cfg.RepoPath == "" {
+		return nil, fmt.Errorf("repo path cannot be empty")
+	}
+
+	if cfg.PathPrefix != "" {
+		cfg.PathPrefix = strings.Trim(cfg.PathPrefix, "/")
+	}
+
+	if cfg.MaxTags <= 0 {
+		slog.Info("max tags not set, using default", "default", defaultMaxTags)
+		cfg.MaxTags = defaultMaxTags
+	}
+
+	p := &GitProvider{
+		cfg: cfg,
+	}
+
+	if _, err := p.git(context.Background(), "rev-parse", "--git-dir"); err != nil {
+		return nil, fmt.Errorf("not a git repository: %w", err)
+	}
+
+	return p, nil
+}
+
+// Get the names of the newest tags in the repository
+func (p *GitProvider) ListVersions(ctx context.Context) ([]Version, error) {
+	p.fetch(ctx)
+
+	out, err := p.git(ctx, "for-each-ref", "--sort=-creatordate", "--count="+strconv.Itoa(p.cfg.MaxTags), "--format=%(refname:lstrip=2) %(objectname)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []Version
+	for _, line := range splitLines(out) {
+		// Tag names can not contain spaces so the first space separates the object name
+		name, revision, _ := strings.Cut(line, " ")
+		versions = append(versions, Version{Name: name, Revision: revision})
+	}
+
+	return versions, nil
+}
+
+func (p *GitProvider) ListFiles(ctx context.Context, version string) ([]string, error) {
+	// The paths are separated by NUL instead of being quoted when they contain special characters
+	args := []string{"ls-tree", "-r", "-z", "--name-only", "refs/tags/" + version}
+	if p.cfg.PathPrefix != "" {
+		args = append(args, "--", p.cfg.PathPrefix+"/")
+	}
+
+	out, err := p.git(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path == "" {
+			continue
+		}
+
+		if f, ok := treeFile(version, path, p.cfg.PathPrefix, p.cfg.FileSuffix); ok {
+			files = append(files, f)
+		}
+	}
+
+	return files, nil
+}
+
+// GetPath returns an empty path since the files cannot be reached outside of the server.
+// The git provider must be served through the proxy.
+func (p *GitProvider) GetPath(version, file string) string {
+	return ""
+}
+
+func (p *GitProvider) DownloadFile(ctx context.Context, version, file string) ([]byte, error) {
+	return p.git(ctx, "cat-file", "blob", "refs/tags/"+version+":"+repoFilePath(p.cfg.PathPrefix, file, p.cfg.FileSuffix))
+}
+
+// fetch fetches from the remote if one is configured and the fetch interval has passed.
+// A failed fetch is only logged since the local state is still usable.
+func (p *GitProvider) fetch(ctx context.Context) {
+	if p.cfg.Remote == "" {
+		return
+	}
+
+	p.fetchLock.Lock()
+	defer p.fetchLock.Unlock()
+
+	if time.Since(p.lastFetch) < p.cfg.FetchInterval {
+		return
+	}
+
+	slog.Debug("fetching from remote", "remote", p.cfg.Remote)
+	if _, err := p.git(ctx, "fetch", "--prune", "--tags", "--force", p.cfg.Remote); err != nil {
+		slog.Warn("failed to fetch from remote, using local state", "remote", p.cfg.Remote, "error", err)
+		return
+	}
+
+	p.lastFetch = time.Now()
+}
+
+func (p *GitProvider) git(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", p.cfg.RepoPath}, args...)...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && isGitNotFound(msg) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, msg)
+		}
+
+		if msg != "" {
+			return nil, fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func isGitNotFound(msg string) bool {
+	return strings.Contains(msg, "does not exist") ||
+		strings.Contains(msg, "Not a valid object name") ||
+		strings.Contains(msg, "invalid object name") ||
+		strings.Contains(msg, "not a tree object")
+}
+
+func splitLines(b []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// newTestRepo creates a repository with the files committed and tagged as v1.0.0,
+// followed by a commit removing one of them tagged as v2.0.0.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"api/a.swagger.json":          `{"name":"a"}`,
+		"api/nested/b.swagger.json":   `{"name":"b"}`,
+		"api/ spaced .swagger.json":   `{"name":"spaced"}`,
+		"api/quoted\"\t.swagger.json": `{"name":"quoted"}`,
+		"api/README.md":               "readme",
+		"apis/other.swagger.json":     `{"name":"other"}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(args ...string) {
+		t.Helper()
+
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	run("init", "--quiet")
+	run("add", "--all")
+	run("commit", "--quiet", "--message", "first")
+	run("tag", "v1.0.0")
+	run("rm", "--quiet", "api/a.swagger.json")
+	run("commit", "--quiet", "--message", "second")
+	// Both tags are created in the same second, so the creator date does not order them
+	run("tag", "--annotate", "--message", "second", "v2.0.0")
+
+	return dir
+}
+
+func TestGitListVersions(t *testing.T) {
+	p, err := NewGit(&GitConfig{RepoPath: newTestRepo(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := p.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, v := range versions {
+		if v.Revision == "" {
+			t.Errorf("version %s has no revision", v.Name)
+		}
+		names = append(names, v.Name)
+	}
+	slices.Sort(names)
+
+	if want := []string{"v1.0.0", "v2.0.0"}; !slices.Equal(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
+
+func TestGitListFiles(t *testing.T) {
+	p, err := NewGit(&GitConfig{RepoPath: newTestRepo(t), PathPrefix: "/api/", FileSuffix: ".swagger.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:    "keeps special characters in names",
+			version: "v1.0.0",
+			want:    []string{" spaced ", "a", "nested/b", "quoted\"\t"},
+		},
+		{
+			name:    "reads the tree of the tag",
+			version: "v2.0.0",
+			want:    []string{" spaced ", "nested/b", "quoted\"\t"},
+		},
+		{
+			name:    "unknown tag",
+			version: "v3.0.0",
+			wantErr: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := p.ListFiles(context.Background(), tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			slices.Sort(files)
+			if !slices.Equal(files, tt.want) {
+				t.Errorf("got %q, want %q", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitDownloadFile(t *testing.T) {
+	p, err := NewGit(&GitConfig{RepoPath: newTestRepo(t), PathPrefix: "api", FileSuffix: ".swagger.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		file    string
+		want    string
+		wantErr error
+	}{
+		{name: "nested", version: "v1.0.0", file: "nested/b", want: `{"name":"b"}`},
+		{name: "spaced", version: "v1.0.0", file: " spaced ", want: `{"name":"spaced"}`},
+		{name: "removed in a later tag", version: "v2.0.0", file: "a", wantErr: ErrNotFound},
+		{name: "unknown tag", version: "v3.0.0", file: "a", wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := p.DownloadFile(context.Background(), tt.version, tt.file)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if string(data) != tt.want {
+				t.Errorf("got %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGitNotARepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	if _, err := NewGit(&GitConfig{RepoPath: t.TempDir()}); err == nil {
+		t.Error("expected an error for a directory that is not a repository")
+	}
+}
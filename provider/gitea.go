@@ -0,0 +1,171 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	giteaTreePageSize = 1000
+)
+
+// GiteaProvider works against both Gitea and Forgejo since they share the same API.
+type GiteaProvider struct {
+	*forgeClient
+
+	cfg *GiteaConfig
+}
+
+type GiteaConfig struct {
+	// The url of the Gitea instance, e.g. https://gitea.example.com
+	BaseURL    string
+	Owner      string
+	Repo       string
+	PathPrefix string
+	FileSuffix string
+	MaxTags    int
+	AuthToken  string
+}
+
+func NewGitea(cfg *GiteaConfig) (*GiteaProvider, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("base url cannot be empty")
+	}
+
+	if cfg.Owner == "" {
+		return nil, fmt.Errorf("owner cannot be empty")
+	}
+
+	if cfg.Repo == "" {
+		return nil, fmt.Errorf("repo cannot be empty")
+	}
+
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	if cfg.PathPrefix != "" {
+		cfg.PathPrefix = strings.Trim(cfg.PathPrefix, "/")
+	}
+
+	if cfg.MaxTags <= 0 {
+		slog.Info("max tags not set, using default", "default", defaultMaxTags)
+		cfg.MaxTags = defaultMaxTags
+	}
+
+	baseUrl, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+
+	return &GiteaProvider{
+		forgeClient: &forgeClient{
+			client: &http.Client{Timeout: 30 * time.Second},
+			apiUrl: fmt.Sprint(baseUrl.String(), "/api/v1/repos/", url.PathEscape(cfg.Owner), "/", url.PathEscape(cfg.Repo)),
+			authorize: func(req *http.Request) {
+				if cfg.AuthToken != "" {
+					req.Header.Set("Authorization", "token "+cfg.AuthToken)
+				}
+			},
+		},
+		cfg: cfg,
+	}, nil
+}
+
+// Get the names of all tags in the repository
+func (p *GiteaProvider) ListVersions(ctx context.Context) ([]Version, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(p.cfg.MaxTags))
+
+	var tags []struct {
+		Name   string `json:"name"`
+		Commit struct {
+			SHA string `json:"sha"`
+		} `json:"commit"`
+	}
+	if _, err := p.getJSON(ctx, "/tags", query, &tags); err != nil {
+		return nil, err
+	}
+
+	var versions []Version
+	for _, tag := range tags {
+		versions = append(versions, Version{Name: tag.Name, Revision: tag.Commit.SHA})
+	}
+
+	return versions, nil
+}
+
+func (p *GiteaProvider) ListFiles(ctx context.Context, version string) ([]string, error) {
+	query := url.Values{}
+	query.Set("recursive", "true")
+	query.Set("per_page", strconv.Itoa(giteaTreePageSize))
+
+	var files []string
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		var tree struct {
+			Entries []struct {
+				Type string `json:"type"`
+				Path string `json:"path"`
+			} `json:"tree"`
+			Truncated bool `json:"truncated"`
+		}
+		if _, err := p.getJSON(ctx, "/git/trees/"+url.PathEscape(version), query, &tree); err != nil {
+			return nil, err
+		}
+
+		for _, entry := range tree.Entries {
+			// Only include files that are in the path prefix and are blobs
+			if entry.Type != "blob" {
+				continue
+			}
+
+			if f, ok := treeFile(version, entry.Path, p.cfg.PathPrefix, p.cfg.FileSuffix); ok {
+				files = append(files, f)
+			}
+		}
+
+		if !tree.Truncated || len(tree.Entries) == 0 {
+			break
+		}
+	}
+
+	return files, nil
+}
+
+// GetPath returns the url of the raw file through the API since it resolves the ref
+// regardless of it being a tag, a branch or a commit.
+func (p *GiteaProvider) GetPath(version, file string) string {
+	return fmt.Sprint(p.apiUrl, "/raw/", escapePath(p.filePath(file)), "?ref=", url.QueryEscape(version))
+}
+
+// RequiresProxy always reports that the proxy is required, even for public repositories.
+// Gitea does not send CORS headers unless enabled by the instance, which it is not by default,
+// so the browser can not fetch the files from the links of GetPath.
+func (p *GiteaProvider) RequiresProxy(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func (p *GiteaProvider) DownloadFile(ctx context.Context, version, file string) ([]byte, error) {
+	query := url.Values{}
+	query.Set("ref", version)
+
+	resp, err := p.get(ctx, "/raw/"+escapePath(p.filePath(file)), query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// filePath returns the path of a file relative to the root of the repository.
+func (p *GiteaProvider) filePath(file string) string {
+	return repoFilePath(p.cfg.PathPrefix, file, p.cfg.FileSuffix)
+}
@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newGiteaStandIn serves a repository with two tags and a tree truncated into pages.
+func newGiteaStandIn(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	const repo = "/api/v1/repos/team/specs"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path := r.URL.EscapedPath(); {
+		case r.Header.Get("Authorization") != "token token":
+			w.WriteHeader(http.StatusUnauthorized)
+
+		case path == repo+"/tags":
+			fmt.Fprint(w, `[{"name":"v1.1.0","commit":{"sha":"bbb"}},{"name":"v1.0.0","commit":{"sha":"aaa"}}]`)
+
+		case path == repo+"/git/trees/v1.0.0":
+			if r.URL.Query().Get("page") == "1" {
+				fmt.Fprint(w, `{"tree":[{"type":"tree","path":"api/nested"},{"type":"blob","path":"api/a.swagger.json"},{"type":"blob","path":"api/README.md"}],"truncated":true}`)
+				return
+			}
+			fmt.Fprint(w, `{"tree":[{"type":"blob","path":"api/nested/b.swagger.json"},{"type":"blob","path":"apis/other.swagger.json"}],"truncated":false}`)
+
+		case path == repo+"/raw/api/a.swagger.json" && r.URL.Query().Get("ref") == "v1.0.0":
+			fmt.Fprint(w, `{"swagger":"2.0"}`)
+
+		case path == repo+"/raw/api/limited.swagger.json":
+			w.Header().Set("X-RateLimit-Limit", "100")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newTestGitea(t *testing.T) *GiteaProvider {
+	t.Helper()
+
+	srv := newGiteaStandIn(t)
+	p, err := NewGitea(&GiteaConfig{
+		BaseURL:    srv.URL,
+		Owner:      "team",
+		Repo:       "specs",
+		PathPrefix: "api/",
+		FileSuffix: ".swagger.json",
+		AuthToken:  "token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestGiteaListVersions(t *testing.T) {
+	p := newTestGitea(t)
+
+	versions, err := p.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Version{{Name: "v1.1.0", Revision: "bbb"}, {Name: "v1.0.0", Revision: "aaa"}}
+	if !slices.Equal(versions, want) {
+		t.Errorf("got %v, want %v", versions, want)
+	}
+}
+
+func TestGiteaListFiles(t *testing.T) {
+	p := newTestGitea(t)
+
+	tests := []struct {
+		name    string
+		version string
+		want    []string
+		wantErr error
+	}{
+		{name: "follows truncated pages and filters prefix and suffix", version: "v1.0.0", want: []string{"a", "nested/b"}},
+		{name: "unknown ref", version: "v9.9.9", wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := p.ListFiles(context.Background(), tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if !slices.Equal(files, tt.want) {
+				t.Errorf("got %v, want %v", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestGiteaDownloadFile(t *testing.T) {
+	p := newTestGitea(t)
+
+	tests := []struct {
+		name          string
+		file          string
+		want          string
+		wantErr       error
+		wantRateLimit bool
+	}{
+		{name: "found", file: "a", want: `{"swagger":"2.0"}`},
+		{name: "not found", file: "missing", wantErr: ErrNotFound},
+		{name: "rate limited", file: "limited", wantRateLimit: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := p.DownloadFile(context.Background(), "v1.0.0", tt.file)
+
+			if tt.wantRateLimit {
+				var rlErr RateLimitError
+				if !errors.As(err, &rlErr) {
+					t.Fatalf("got error %v, want a RateLimitError", err)
+				}
+				if rlErr.Limit != 100 || rlErr.Remaining != 0 || rlErr.RetryAfter != 30*time.Second {
+					t.Errorf("got %+v, want limit 100, remaining 0 and retry after 30s", rlErr)
+				}
+				if limit, ok := p.RateLimit(); !ok || limit.Limit != 100 {
+					t.Errorf("rate limit was not tracked, got %+v", limit)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if string(data) != tt.want {
+				t.Errorf("got %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestGiteaRequiresProxy(t *testing.T) {
+	p := newTestGitea(t)
+
+	// Gitea sends no CORS headers by default, so even public repositories need the proxy
+	required, err := p.RequiresProxy(context.Background())
+	if err != nil || !required {
+		t.Errorf("got %t, %v, want the proxy to be required", required, err)
+	}
+}
+
+func TestGiteaGetPath(t *testing.T) {
+	p, err := NewGitea(&GiteaConfig{
+		BaseURL:    "https://gitea.example.com/",
+		Owner:      "team",
+		Repo:       "specs",
+		PathPrefix: "api/",
+		FileSuffix: ".json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		file    string
+		want    string
+	}{
+		{
+			name:    "plain",
+			version: "v1.0.0",
+			file:    "nested/a",
+			want:    "https://gitea.example.com/api/v1/repos/team/specs/raw/api/nested/a.json?ref=v1.0.0",
+		},
+		{
+			name:    "special characters",
+			version: "release/1.0",
+			file:    "what? #1/a b",
+			want:    "https://gitea.example.com/api/v1/repos/team/specs/raw/api/what%3F%20%231/a%20b.json?ref=release%2F1.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.GetPath(tt.version, tt.file); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
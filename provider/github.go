@@ -1,39 +1,92 @@
 package provider
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
 	"net/url"
+	"path"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/go-github/v58/github"
 )
 
 const (
-	defaultMaxTags = 10
+	defaultMaxTags         = 10
+	defaultMaxBranches     = 10
+	defaultMaxPullRequests = 10
+
+	pullRequestVersionPrefix = "pr-"
+
+	defaultGithubRawURL = "https://raw.githubusercontent.com"
 )
 
 var (
 	ErrNotFound = fmt.Errorf("not found")
+	// Returned when nothing changed since the last call
+	ErrNotModified = fmt.Errorf("not modified")
 )
 
 type GithubProvider struct {
+	rateLimitTracker
+
 	client *github.Client
 
 	cfg     *GithubConfig
 	rootUrl string
+
+	refsLock sync.RWMutex
+	// The git refs to use for the versions that are not tags, keyed by version
+	refs map[string]string
+	// If any of the lists of versions changed since they were last returned by ListVersions
+	versionsChanged bool
+
+	etagsLock sync.Mutex
+	// The results of conditional requests keyed by url
+	etags map[string]etagEntry
+
+	commitDatesLock sync.Mutex
+	// The dates of the commits of the tags keyed by SHA, used when ordering tags by date
+	commitDates map[string]time.Time
 }
 
 type GithubConfig struct {
 	Owner string
 	Repo  string
+	// The url of the API of a GitHub Enterprise Server, e.g. https://github.example.com/api/v3
+	// The /api/v3 suffix is added if it is missing. Uses github.com if empty.
+	BaseURL string
+	// The upload url of a GitHub Enterprise Server, defaults to BaseURL
+	UploadURL string
+	// The url that raw files are served from, used for the links to the files.
+	// Defaults to raw.githubusercontent.com, or {host}/raw of the BaseURL.
+	RawURL string
+	// A PEM file with certificates to trust in addition to the ones of the system
+	CABundle string
 	// TODO: Verify that an empty PathPrefix works as expected
 	PathPrefix string
 	FileSuffix string
 	MaxTags    int
 	AuthToken  string
+	// Authenticate as a GitHub App installation instead of using AuthToken
+	App *GithubAppConfig
+	// How the newest MaxTags tags are picked, TagOrderSemver or TagOrderDate
+	TagOrder string
+	// Use the published releases instead of all tags, drafts and prereleases are skipped
+	UseReleases bool
+	// Do not use tags as versions
+	SkipTags bool
+	// Glob patterns (as in path.Match) of branches to use as versions
+	Branches    []string
+	MaxBranches int
+	// Use the open pull requests as versions, named pr-{number}
+	PullRequests    bool
+	MaxPullRequests int
 }
 
 func NewGithub(cfg *GithubConfig) (*GithubProvider, error) {
@@ -49,47 +102,300 @@ func NewGithub(cfg *GithubConfig) (*GithubProvider, error) {
 		cfg.PathPrefix = strings.Trim(cfg.PathPrefix, "/")
 	}
 
-	if cfg.MaxTags <= 0 {
+	if cfg.MaxTags <= 0 && !cfg.SkipTags {
 		slog.Info("max tags not set, using default", "default", defaultMaxTags)
 		cfg.MaxTags = defaultMaxTags
 	}
 
-	rootUrl, err := url.Parse(fmt.Sprintf("https://raw.githubusercontent.com/%s/%s", cfg.Owner, cfg.Repo))
+	switch cfg.TagOrder {
+	case "":
+		slog.Info("tag order not set, using default", "default", TagOrderSemver)
+		cfg.TagOrder = TagOrderSemver
+	case TagOrderSemver, TagOrderDate:
+	default:
+		return nil, fmt.Errorf("invalid tag order %q, must be %q or %q", cfg.TagOrder, TagOrderSemver, TagOrderDate)
+	}
+
+	for _, pattern := range cfg.Branches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid branch pattern %q: %w", pattern, err)
+		}
+	}
+
+	if len(cfg.Branches) > 0 && cfg.MaxBranches <= 0 {
+		slog.Info("max branches not set, using default", "default", defaultMaxBranches)
+		cfg.MaxBranches = defaultMaxBranches
+	}
+
+	if cfg.PullRequests && cfg.MaxPullRequests <= 0 {
+		slog.Info("max pull requests not set, using default", "default", defaultMaxPullRequests)
+		cfg.MaxPullRequests = defaultMaxPullRequests
+	}
+
+	if cfg.SkipTags && len(cfg.Branches) == 0 && !cfg.PullRequests {
+		return nil, fmt.Errorf("no version sources configured")
+	}
+
+	rawUrl, err := githubRawURL(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	rootUrl, err := url.Parse(fmt.Sprintf("%s/%s/%s", rawUrl, cfg.Owner, cfg.Repo))
 	if err != nil {
 		return nil, fmt.Errorf("invalid root url: %w", err)
 	}
 
-	cl := github.NewClient(nil)
+	if cfg.AuthToken != "" && cfg.App != nil {
+		return nil, fmt.Errorf("auth token and app cannot both be set")
+	}
+
+	// The client copies the http client it is given, so the transport has to be complete before it is created
+	var transport http.RoundTripper
+	if cfg.CABundle != "" {
+		transport, err = transportWithCABundle(cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.App != nil {
+		apiURL, err := githubAPIURL(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		transport, err = newGithubAppTransport(cfg.App, apiURL, transport)
+		if err != nil {
+			return nil, fmt.Errorf("invalid app config: %w", err)
+		}
+	}
+
+	cl, err := newGithubClient(cfg, &http.Client{Transport: transport})
+	if err != nil {
+		return nil, err
+	}
+
 	if cfg.AuthToken != "" {
-		cl.WithAuthToken(cfg.AuthToken)
+		cl = cl.WithAuthToken(cfg.AuthToken)
 	}
 
 	return &GithubProvider{
 		cfg:     cfg,
 		client:  cl,
 		rootUrl: rootUrl.String(),
+		refs:    make(map[string]string),
+		etags:   make(map[string]etagEntry),
+
+		commitDates: make(map[string]time.Time),
 	}, nil
 }
 
-// Get the names of all tags in the repository
-func (p *GithubProvider) ListVersions(ctx context.Context) ([]string, error) {
-	tags, _, err := p.client.Repositories.ListTags(ctx, p.cfg.Owner, p.cfg.Repo, &github.ListOptions{PerPage: p.cfg.MaxTags})
+// newGithubClient creates a client for github.com, or for the enterprise server if a base url is configured.
+func newGithubClient(cfg *GithubConfig, httpClient *http.Client) (*github.Client, error) {
+	cl := github.NewClient(httpClient)
+	if cfg.BaseURL == "" {
+		return cl, nil
+	}
+
+	cl, err := cl.WithEnterpriseURLs(cfg.BaseURL, cmp.Or(cfg.UploadURL, cfg.BaseURL))
 	if err != nil {
-		return nil, handleError(err)
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+
+	return cl, nil
+}
+
+// githubAPIURL returns the url of the API ending with a slash, the same way the client resolves it.
+func githubAPIURL(cfg *GithubConfig) (string, error) {
+	cl, err := newGithubClient(cfg, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return cl.BaseURL.String(), nil
+}
+
+// githubRawURL returns the url that raw files are served from, without a trailing slash.
+func githubRawURL(cfg *GithubConfig) (string, error) {
+	if cfg.RawURL != "" {
+		return strings.TrimRight(cfg.RawURL, "/"), nil
+	}
+
+	if cfg.BaseURL == "" {
+		return defaultGithubRawURL, nil
+	}
+
+	// GitHub Enterprise Server serves raw files from the same host as the API
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base url: %w", err)
+	}
+
+	return fmt.Sprintf("%s://%s/raw", u.Scheme, u.Host), nil
+}
+
+// Get the tags, branches and pull requests to use as versions.
+// ErrNotModified is returned if none of them changed since the last call.
+func (p *GithubProvider) ListVersions(ctx context.Context) ([]Version, error) {
+	var versions []Version
+	refs := make(map[string]string)
+
+	// Branch names are changed to be used as versions, so they can collide with each other,
+	// with a tag or with a pull request. The first version with a name is kept.
+	seen := make(map[string]string)
+	addVersion := func(v Version, ref string) bool {
+		if other, ok := seen[v.Name]; ok {
+			slog.Warn("version name is already used, skipping", "version", v.Name, "ref", ref, "used_by", other)
+			return false
+		}
+
+		seen[v.Name] = ref
+		versions = append(versions, v)
+		return true
+	}
+
+	if !p.cfg.SkipTags {
+		tags, err := p.listTags(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range tags {
+			addVersion(tag, "tag "+tag.Name)
+		}
+	}
+
+	if len(p.cfg.Branches) > 0 {
+		branches, err := p.listBranches(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, branch := range branches {
+			// Slashes are not allowed in versions since they are used in the urls
+			name := strings.ReplaceAll(branch.GetName(), "/", "-")
+			added := addVersion(Version{
+				Name:     name,
+				Revision: branch.GetCommit().GetSHA(),
+				Moving:   true,
+			}, "branch "+branch.GetName())
+			if added {
+				refs[name] = branch.GetCommit().GetSHA()
+			}
+		}
 	}
 
-	var versions []string
-	for _, tag := range tags {
-		versions = append(versions, *tag.Name)
+	if p.cfg.PullRequests {
+		u := fmt.Sprintf("repos/%v/%v/pulls?state=open&per_page=%d", p.cfg.Owner, p.cfg.Repo, p.cfg.MaxPullRequests)
+
+		var prs []*github.PullRequest
+		resp, cached, err := p.conditionalGet(ctx, u, &prs)
+		if err != nil {
+			return nil, err
+		}
+		if cached != nil {
+			prs = cached.([]*github.PullRequest)
+		} else {
+			p.storeConditional(u, resp, prs)
+			p.markVersionsChanged()
+		}
+
+		for _, pr := range prs {
+			name := fmt.Sprint(pullRequestVersionPrefix, pr.GetNumber())
+			added := addVersion(Version{
+				Name:     name,
+				Revision: pr.GetHead().GetSHA(),
+				Moving:   true,
+			}, fmt.Sprint("pull request ", pr.GetNumber()))
+			if added {
+				refs[name] = pr.GetHead().GetSHA()
+			}
+		}
 	}
 
+	p.refsLock.Lock()
+	defer p.refsLock.Unlock()
+
+	if !p.versionsChanged {
+		return nil, ErrNotModified
+	}
+
+	p.refs = refs
+	p.versionsChanged = false
+	p.pruneTrees(versions, refs)
+
 	return versions, nil
 }
 
+// markVersionsChanged records that a list of versions changed.
+// It stays marked until the versions are returned, so that the change
+// is not lost if listing the versions fails before that.
+func (p *GithubProvider) markVersionsChanged() {
+	p.refsLock.Lock()
+	defer p.refsLock.Unlock()
+
+	p.versionsChanged = true
+}
+
+// listBranches returns the branches matching any of the configured patterns.
+func (p *GithubProvider) listBranches(ctx context.Context) ([]*github.Branch, error) {
+	u := fmt.Sprintf("repos/%v/%v/branches?per_page=%d", p.cfg.Owner, p.cfg.Repo, githubPageSize)
+	branches, err := listVersionPages[*github.Branch](ctx, p, u)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []*github.Branch
+	for _, branch := range branches {
+		if !p.matchesBranch(branch.GetName()) {
+			continue
+		}
+
+		matching = append(matching, branch)
+		if len(matching) >= p.cfg.MaxBranches {
+			break
+		}
+	}
+
+	return matching, nil
+}
+
+func (p *GithubProvider) matchesBranch(name string) bool {
+	for _, pattern := range p.cfg.Branches {
+		// The patterns are validated when creating the provider
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ref returns the git ref of a version.
+// Branches and pull requests are resolved to the commit they pointed to when the versions were last listed.
+func (p *GithubProvider) ref(version string) string {
+	p.refsLock.RLock()
+	defer p.refsLock.RUnlock()
+
+	if ref, ok := p.refs[version]; ok {
+		return ref
+	}
+
+	return version
+}
+
 func (p *GithubProvider) ListFiles(ctx context.Context, version string) ([]string, error) {
-	tree, _, err := p.client.Git.GetTree(ctx, p.cfg.Owner, p.cfg.Repo, version, true)
+	u := p.treeURL(p.ref(version))
+
+	tree := new(github.Tree)
+	resp, cached, err := p.conditionalGet(ctx, u, tree)
 	if err != nil {
-		return nil, handleError(err)
+		return nil, err
+	}
+	// Only the files are kept since the tree can be large
+	if cached != nil {
+		return cached.([]string), nil
 	}
 
 	var files []string
@@ -110,16 +416,41 @@ func (p *GithubProvider) ListFiles(ctx context.Context, version string) ([]strin
 		}
 	}
 
+	p.storeConditional(u, resp, files)
+
 	return files, nil
 }
 
+// treesURL is the url all trees of the repository start with.
+func (p *GithubProvider) treesURL() string {
+	return fmt.Sprintf("repos/%v/%v/git/trees/", p.cfg.Owner, p.cfg.Repo)
+}
+
+func (p *GithubProvider) treeURL(ref string) string {
+	return fmt.Sprint(p.treesURL(), ref, "?recursive=1")
+}
+
 func (p *GithubProvider) GetPath(version, file string) string {
-	return fmt.Sprint(p.rootUrl, "/", version, "/", p.cfg.PathPrefix, "/", file, p.cfg.FileSuffix)
+	return fmt.Sprint(p.rootUrl, "/", p.ref(version), "/", p.cfg.PathPrefix, "/", file, p.cfg.FileSuffix)
+}
+
+// RequiresProxy reports if the repository is not public,
+// in which case the browser can not fetch the files from the links of GetPath.
+func (p *GithubProvider) RequiresProxy(ctx context.Context) (bool, error) {
+	repo, resp, err := p.client.Repositories.Get(ctx, p.cfg.Owner, p.cfg.Repo)
+	p.trackResponse(resp)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	// Internal repositories of an enterprise are not private, but still require authentication
+	return repo.GetPrivate() || (repo.GetVisibility() != "" && repo.GetVisibility() != "public"), nil
 }
 
 func (p *GithubProvider) DownloadFile(ctx context.Context, version, file string) ([]byte, error) {
 	path := fmt.Sprint(p.cfg.PathPrefix, "/", file, p.cfg.FileSuffix)
-	content, resp, err := p.client.Repositories.DownloadContents(ctx, p.cfg.Owner, p.cfg.Repo, path, &github.RepositoryContentGetOptions{Ref: version})
+	content, resp, err := p.client.Repositories.DownloadContents(ctx, p.cfg.Owner, p.cfg.Repo, path, &github.RepositoryContentGetOptions{Ref: p.ref(version)})
+	p.trackResponse(resp)
 	if err != nil {
 		if strings.Contains(err.Error(), "No commit found") {
 			return nil, fmt.Errorf("%w: version=%s", ErrNotFound, version)
@@ -138,12 +469,29 @@ func (p *GithubProvider) DownloadFile(ctx context.Context, version, file string)
 	return io.ReadAll(content)
 }
 
+// trackResponse keeps the rate limit reported in the response.
+func (p *GithubProvider) trackResponse(resp *github.Response) {
+	if resp == nil || resp.Rate.Limit == 0 {
+		return
+	}
+
+	p.track(rateLimitFromGithub(resp.Rate))
+}
+
+func rateLimitFromGithub(rate github.Rate) RateLimit {
+	return RateLimit{
+		Limit:     rate.Limit,
+		Remaining: rate.Remaining,
+		Reset:     rate.Reset.Time,
+	}
+}
+
 func handleError(err error) error {
 	if err, ok := err.(*github.RateLimitError); ok {
-		return NewRateLimitError("rate limit reached", "limit", err.Rate.Limit, "reset", err.Rate.Reset)
+		return newRateLimitError("rate limit reached", rateLimitFromGithub(err.Rate), 0)
 	}
 	if err, ok := err.(*github.AbuseRateLimitError); ok {
-		return NewRateLimitError("abuse rate limit reached", "retry_after", err.RetryAfter)
+		return newRateLimitError("abuse rate limit reached", RateLimit{}, err.GetRetryAfter())
 	}
 	return err
 }
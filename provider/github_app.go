@@ -0,0 +1,208 @@
+package provider
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// GitHub does not accept app tokens valid for more than 10 minutes
+	githubAppJWTLifetime = 9 * time.Minute
+	// Installation tokens are refreshed this long before they expire
+	githubTokenRefreshMargin = 5 * time.Minute
+)
+
+// GithubAppConfig is the config to authenticate as a GitHub App installation.
+type GithubAppConfig struct {
+	AppID          int64
+	InstallationID int64
+	// The PEM encoded private key of the app
+	PrivateKey string
+	// A file containing the PEM encoded private key of the app, used if PrivateKey is empty
+	PrivateKeyFile string
+}
+
+// githubAppTransport authenticates the requests as a GitHub App installation.
+// Installation tokens are created when needed and refreshed before they expire.
+type githubAppTransport struct {
+	cfg *GithubAppConfig
+	key *rsa.PrivateKey
+	// The url of the API the installation tokens are created at, ending with a slash
+	apiURL string
+	// Used to create the installation tokens
+	client *http.Client
+	next   http.RoundTripper
+
+	lock    sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// newGithubAppTransport creates a transport authenticating as the app installation,
+// sending the requests with next, or http.DefaultTransport if it is nil.
+func newGithubAppTransport(cfg *GithubAppConfig, apiURL string, next http.RoundTripper) (*githubAppTransport, error) {
+	if cfg.AppID == 0 {
+		return nil, fmt.Errorf("app id cannot be empty")
+	}
+
+	if cfg.InstallationID == 0 {
+		return nil, fmt.Errorf("installation id cannot be empty")
+	}
+
+	keyPEM := []byte(cfg.PrivateKey)
+	if cfg.PrivateKey == "" {
+		if cfg.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("private key cannot be empty")
+		}
+
+		var err error
+		keyPEM, err = os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key: %w", err)
+		}
+	}
+
+	key, err := parseRSAPrivateKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &githubAppTransport{
+		cfg:    cfg,
+		key:    key,
+		apiURL: apiURL,
+		client: &http.Client{Transport: next, Timeout: 30 * time.Second},
+		next:   next,
+	}, nil
+}
+
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an RSA key")
+	}
+
+	return key, nil
+}
+
+func (t *githubAppTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.installationToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// A RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "token "+token)
+
+	return t.next.RoundTrip(req)
+}
+
+// installationToken returns a valid installation token, creating a new one if needed.
+func (t *githubAppTransport) installationToken(req *http.Request) (string, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.token != "" && time.Until(t.expires) > githubTokenRefreshMargin {
+		return t.token, nil
+	}
+
+	jwt, err := t.appJWT()
+	if err != nil {
+		return "", fmt.Errorf("failed to create app token: %w", err)
+	}
+
+	u := fmt.Sprintf("%sapp/installations/%d/access_tokens", t.apiURL, t.cfg.InstallationID)
+	tokenReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, u, nil)
+	if err != nil {
+		return "", err
+	}
+	tokenReq.Header.Set("Authorization", "Bearer "+jwt)
+	tokenReq.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := t.client.Do(tokenReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to create installation token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("failed to create installation token: unexpected status code: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode installation token: %w", err)
+	}
+
+	t.token = body.Token
+	t.expires = body.ExpiresAt
+
+	return t.token, nil
+}
+
+// appJWT creates a JSON Web Token authenticating as the app itself, signed with its private key.
+func (t *githubAppTransport) appJWT() (string, error) {
+	now := time.Now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]any{
+		// Backdated to allow for clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(githubAppJWTLifetime).Unix(),
+		"iss": fmt.Sprint(t.cfg.AppID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := strings.Join([]string{
+		base64.RawURLEncoding.EncodeToString(header),
+		base64.RawURLEncoding.EncodeToString(claims),
+	}, ".")
+
+	sum := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, t.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
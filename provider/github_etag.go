@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v58/github"
+)
+
+// etagEntry is the result of a conditional request and the ETag of the response it was made from.
+type etagEntry struct {
+	etag string
+	// The result made from the response, its type depends on the request
+	value any
+}
+
+// conditionalGet performs a GET request against the API and decodes the response into v.
+// The ETag of the last response from the url is sent so that an unchanged response is returned
+// as 304 Not Modified, which does not count against the rate limit.
+//
+// If the response was not modified, the result stored for the url is returned instead and v is left untouched.
+// Otherwise the result has to be stored with storeConditional to be returned next time.
+func (p *GithubProvider) conditionalGet(ctx context.Context, u string, v any) (resp *github.Response, cached any, err error) {
+	req, err := p.client.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.etagsLock.Lock()
+	entry, ok := p.etags[u]
+	p.etagsLock.Unlock()
+
+	if ok {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+
+	resp, err = p.client.Do(ctx, req, v)
+	p.trackResponse(resp)
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if ok && errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusNotModified {
+			return resp, entry.value, nil
+		}
+		return resp, nil, handleError(err)
+	}
+
+	return resp, nil, nil
+}
+
+// storeConditional stores the result made from the response of a conditional request.
+func (p *GithubProvider) storeConditional(u string, resp *github.Response, value any) {
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return
+	}
+
+	p.etagsLock.Lock()
+	defer p.etagsLock.Unlock()
+
+	p.etags[u] = etagEntry{etag: etag, value: value}
+}
+
+// pruneTrees drops the stored trees of the refs no longer used by any version.
+// Every commit of a branch or pull request has its own tree url, so they would otherwise pile up.
+func (p *GithubProvider) pruneTrees(versions []Version, refs map[string]string) {
+	keep := make(map[string]struct{}, len(versions))
+	for _, v := range versions {
+		ref, ok := refs[v.Name]
+		if !ok {
+			ref = v.Name
+		}
+		keep[p.treeURL(ref)] = struct{}{}
+	}
+
+	prefix := p.treesURL()
+
+	p.etagsLock.Lock()
+	defer p.etagsLock.Unlock()
+
+	for u := range p.etags {
+		if _, ok := keep[u]; !ok && strings.HasPrefix(u, prefix) {
+			delete(p.etags, u)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/theleeeo/docs-server/semver"
+)
+
+const (
+	// Pick the newest tags by semantic version, tags that are not semantic versions are picked last
+	TagOrderSemver = "semver"
+	// Pick the newest tags by the date of their commit, or by the publish date of releases
+	TagOrderDate = "date"
+
+	githubPageSize = 100
+)
+
+// githubTag is a tag or a release that can be used as a version.
+type githubTag struct {
+	name string
+	// The commit of the tag, releases are resolved to it once they are picked
+	sha string
+	// Only set when ordering by date
+	date time.Time
+}
+
+// listTags returns the newest tags, or releases if configured, as versions.
+// All pages are listed so that the newest are picked regardless of the order of the API.
+func (p *GithubProvider) listTags(ctx context.Context) ([]Version, error) {
+	var tags []githubTag
+
+	if p.cfg.UseReleases {
+		u := fmt.Sprintf("repos/%v/%v/releases?per_page=%d", p.cfg.Owner, p.cfg.Repo, githubPageSize)
+		releases, err := listVersionPages[*github.RepositoryRelease](ctx, p, u)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, release := range releases {
+			if release.GetDraft() || release.GetPrerelease() {
+				continue
+			}
+
+			tags = append(tags, githubTag{
+				name: release.GetTagName(),
+				date: release.GetPublishedAt().Time,
+			})
+		}
+	} else {
+		u := fmt.Sprintf("repos/%v/%v/tags?per_page=%d", p.cfg.Owner, p.cfg.Repo, githubPageSize)
+		repoTags, err := listVersionPages[*github.RepositoryTag](ctx, p, u)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range repoTags {
+			tags = append(tags, githubTag{
+				name: tag.GetName(),
+				sha:  tag.GetCommit().GetSHA(),
+			})
+		}
+
+		if p.cfg.TagOrder == TagOrderDate {
+			if err := p.setCommitDates(ctx, tags); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sortTags(tags, p.cfg.TagOrder)
+	tags = tags[:min(len(tags), p.cfg.MaxTags)]
+
+	if p.cfg.UseReleases {
+		if err := p.setReleaseCommits(ctx, tags); err != nil {
+			return nil, err
+		}
+	}
+
+	versions := make([]Version, 0, len(tags))
+	for _, tag := range tags {
+		versions = append(versions, Version{
+			Name:     tag.name,
+			Revision: tag.sha,
+		})
+	}
+
+	return versions, nil
+}
+
+// sortTags sorts the tags with the newest first.
+func sortTags(tags []githubTag, order string) {
+	if order == TagOrderDate {
+		slices.SortStableFunc(tags, func(a, b githubTag) int {
+			return b.date.Compare(a.date)
+		})
+		return
+	}
+
+	slices.SortStableFunc(tags, func(a, b githubTag) int {
+		return semver.CompareNamesNewestFirst(a.name, b.name)
+	})
+}
+
+// setReleaseCommits sets the commit of the tag of each release,
+// so that a release is detected as changed if its tag is moved.
+func (p *GithubProvider) setReleaseCommits(ctx context.Context, tags []githubTag) error {
+	u := fmt.Sprintf("repos/%v/%v/tags?per_page=%d", p.cfg.Owner, p.cfg.Repo, githubPageSize)
+	repoTags, err := listVersionPages[*github.RepositoryTag](ctx, p, u)
+	if err != nil {
+		return err
+	}
+
+	commits := make(map[string]string, len(repoTags))
+	for _, tag := range repoTags {
+		commits[tag.GetName()] = tag.GetCommit().GetSHA()
+	}
+
+	for i, tag := range tags {
+		sha, ok := commits[tag.name]
+		if !ok {
+			slog.Warn("the tag of the release was not found, changes to it will not be detected", "tag", tag.name)
+			continue
+		}
+		tags[i].sha = sha
+	}
+
+	return nil
+}
+
+// setCommitDates sets the date of the commit of each tag.
+// Commits never change, so their dates are only requested the first time a commit is seen.
+func (p *GithubProvider) setCommitDates(ctx context.Context, tags []githubTag) error {
+	current := make(map[string]struct{}, len(tags))
+	for i, tag := range tags {
+		current[tag.sha] = struct{}{}
+
+		// The lock is not held during the request, so that other polls do not wait for it
+		p.commitDatesLock.Lock()
+		date, ok := p.commitDates[tag.sha]
+		p.commitDatesLock.Unlock()
+
+		if !ok {
+			commit, resp, err := p.client.Git.GetCommit(ctx, p.cfg.Owner, p.cfg.Repo, tag.sha)
+			p.trackResponse(resp)
+			if err != nil {
+				return fmt.Errorf("failed to get commit of tag %s: %w", tag.name, handleError(err))
+			}
+			date = commit.GetCommitter().GetDate().Time
+
+			// The date is kept right away, so that it is not requested again if a later request fails
+			p.commitDatesLock.Lock()
+			p.commitDates[tag.sha] = date
+			p.commitDatesLock.Unlock()
+		}
+
+		tags[i].date = date
+	}
+
+	// Only the commits of the current tags are kept, which are only known once all of them were seen
+	p.commitDatesLock.Lock()
+	defer p.commitDatesLock.Unlock()
+
+	for sha := range p.commitDates {
+		if _, ok := current[sha]; !ok {
+			delete(p.commitDates, sha)
+		}
+	}
+
+	return nil
+}
+
+// listPage is a page of a list and the number of the next page, 0 if it is the last one.
+type listPage[T any] struct {
+	items    []T
+	nextPage int
+}
+
+// listVersionPages lists all pages of a list of versions using conditional requests.
+// If any page changed, the versions are marked as changed.
+func listVersionPages[T any](ctx context.Context, p *GithubProvider, u string) ([]T, error) {
+	var all []T
+
+	for page := 1; page != 0; {
+		pageURL := fmt.Sprintf("%s&page=%d", u, page)
+
+		var items []T
+		resp, cached, err := p.conditionalGet(ctx, pageURL, &items)
+		if err != nil {
+			return nil, err
+		}
+
+		var current listPage[T]
+		if cached != nil {
+			current = cached.(listPage[T])
+		} else {
+			current = listPage[T]{items: items, nextPage: resp.NextPage}
+			p.storeConditional(pageURL, resp, current)
+			p.markVersionsChanged()
+		}
+
+		all = append(all, current.items...)
+		page = current.nextPage
+	}
+
+	return all, nil
+}
@@ -0,0 +1,432 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newGithubStandIn serves the routes of a GitHub Enterprise Server API keyed by path, e.g. /api/v3/repos/o/r/branches.
+func newGithubStandIn(t *testing.T, routes map[string]http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(githubStandInHandler(routes))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func githubStandInHandler(routes map[string]http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := routes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+			return
+		}
+
+		h(w, r)
+	})
+}
+
+func jsonResponse(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}
+}
+
+func TestGithubListVersionsCollisions(t *testing.T) {
+	srv := newGithubStandIn(t, map[string]http.HandlerFunc{
+		"/api/v3/repos/o/r/branches": jsonResponse(`[
+			{"name":"feature-x","commit":{"sha":"a1"}},
+			{"name":"feature/x","commit":{"sha":"b2"}},
+			{"name":"main","commit":{"sha":"c3"}},
+			{"name":"pr-12","commit":{"sha":"d4"}}
+		]`),
+		"/api/v3/repos/o/r/pulls": jsonResponse(`[
+			{"number":12,"head":{"sha":"e5"}},
+			{"number":13,"head":{"sha":"f6"}}
+		]`),
+	})
+
+	p, err := NewGithub(&GithubConfig{
+		Owner:        "o",
+		Repo:         "r",
+		BaseURL:      srv.URL,
+		SkipTags:     true,
+		Branches:     []string{"*", "feature/*"},
+		PullRequests: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := p.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Version{
+		{Name: "feature-x", Revision: "a1", Moving: true},
+		{Name: "main", Revision: "c3", Moving: true},
+		{Name: "pr-12", Revision: "d4", Moving: true},
+		{Name: "pr-13", Revision: "f6", Moving: true},
+	}
+	if !slices.Equal(versions, want) {
+		t.Errorf("got %v, want %v", versions, want)
+	}
+
+	// The skipped versions must not change the refs of the kept ones
+	for version, wantRef := range map[string]string{"feature-x": "a1", "pr-12": "d4"} {
+		if ref := p.ref(version); ref != wantRef {
+			t.Errorf("got ref %q for %s, want %q", ref, version, wantRef)
+		}
+	}
+}
+
+func TestGithubPrunesTrees(t *testing.T) {
+	var sha string
+	tree := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"`+r.URL.Path+`"`)
+		jsonResponse(`{"tree":[{"path":"api.json","type":"blob"}]}`)(w, r)
+	}
+	srv := newGithubStandIn(t, map[string]http.HandlerFunc{
+		"/api/v3/repos/o/r/branches": func(w http.ResponseWriter, r *http.Request) {
+			jsonResponse(fmt.Sprintf(`[{"name":"main","commit":{"sha":%q}}]`, sha))(w, r)
+		},
+		"/api/v3/repos/o/r/git/trees/a1": tree,
+		"/api/v3/repos/o/r/git/trees/b2": tree,
+	})
+
+	p, err := NewGithub(&GithubConfig{
+		Owner:    "o",
+		Repo:     "r",
+		BaseURL:  srv.URL,
+		SkipTags: true,
+		Branches: []string{"main"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The branch moves on every poll
+	for _, sha = range []string{"a1", "b2", "a1", "b2"} {
+		if _, err := p.ListVersions(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.ListFiles(context.Background(), "main"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var trees []string
+	for u := range p.etags {
+		if strings.HasPrefix(u, p.treesURL()) {
+			trees = append(trees, u)
+		}
+	}
+
+	if want := []string{p.treeURL("b2")}; !slices.Equal(trees, want) {
+		t.Errorf("got stored trees %v, want %v", trees, want)
+	}
+}
+
+func TestGithubListVersionsRevisions(t *testing.T) {
+	var p *GithubProvider
+	commit := func(date string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			// Other polls must not wait for the requests
+			if !p.commitDatesLock.TryLock() {
+				t.Error("the commit dates are locked during the request")
+			} else {
+				p.commitDatesLock.Unlock()
+			}
+			jsonResponse(fmt.Sprintf(`{"committer":{"date":%q}}`, date))(w, r)
+		}
+	}
+	srv := newGithubStandIn(t, map[string]http.HandlerFunc{
+		"/api/v3/repos/o/r/tags": jsonResponse(`[
+			{"name":"v1.1.0","commit":{"sha":"b2"}},
+			{"name":"v1.0.0","commit":{"sha":"a1"}}
+		]`),
+		"/api/v3/repos/o/r/releases": jsonResponse(`[
+			{"tag_name":"v1.1.0","published_at":"2024-02-01T00:00:00Z"},
+			{"tag_name":"v1.0.0","published_at":"2024-01-01T00:00:00Z"},
+			{"tag_name":"v2.0.0-rc.1","prerelease":true}
+		]`),
+		"/api/v3/repos/o/r/git/commits/a1": commit("2024-03-01T00:00:00Z"),
+		"/api/v3/repos/o/r/git/commits/b2": commit("2024-01-01T00:00:00Z"),
+	})
+
+	tests := []struct {
+		name string
+		cfg  GithubConfig
+		want []Version
+	}{
+		{
+			name: "tags by semver",
+			want: []Version{{Name: "v1.1.0", Revision: "b2"}, {Name: "v1.0.0", Revision: "a1"}},
+		},
+		{
+			name: "tags by commit date",
+			cfg:  GithubConfig{TagOrder: TagOrderDate},
+			want: []Version{{Name: "v1.0.0", Revision: "a1"}, {Name: "v1.1.0", Revision: "b2"}},
+		},
+		{
+			name: "releases have the commit of their tag",
+			cfg:  GithubConfig{UseReleases: true, TagOrder: TagOrderDate},
+			want: []Version{{Name: "v1.1.0", Revision: "b2"}, {Name: "v1.0.0", Revision: "a1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Owner, cfg.Repo, cfg.BaseURL = "o", "r", srv.URL
+
+			var err error
+			p, err = NewGithub(&cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			versions, err := p.ListVersions(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(versions, tt.want) {
+				t.Errorf("got %v, want %v", versions, tt.want)
+			}
+		})
+	}
+}
+
+func TestGithubEnterpriseApp(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var srv *httptest.Server
+	authorized := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("Authorization"); got != "token installation-token" {
+				t.Errorf("got authorization %q for %s", got, r.URL.Path)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	srv = httptest.NewTLSServer(githubStandInHandler(map[string]http.HandlerFunc{
+		"/api/v3/app/installations/7/access_tokens": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token":"installation-token","expires_at":%q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+		},
+		"/api/v3/repos/o/r/branches": authorized(jsonResponse(`[{"name":"main","commit":{"sha":"a1"}}]`)),
+		"/api/v3/repos/o/r/contents/docs": authorized(func(w http.ResponseWriter, r *http.Request) {
+			jsonResponse(fmt.Sprintf(`[{"name":"api.json","type":"file","download_url":"%s/raw/o/r/a1/docs/api.json"}]`, srv.URL))(w, r)
+		}),
+		"/raw/o/r/a1/docs/api.json": authorized(jsonResponse(`{"openapi":"3.0.0"}`)),
+	}))
+	t.Cleanup(srv.Close)
+
+	caBundle := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caBundle, certPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewGithub(&GithubConfig{
+		Owner:      "o",
+		Repo:       "r",
+		PathPrefix: "docs",
+		FileSuffix: ".json",
+		BaseURL:    srv.URL,
+		CABundle:   caBundle,
+		SkipTags:   true,
+		Branches:   []string{"main"},
+		App: &GithubAppConfig{
+			AppID:          1,
+			InstallationID: 7,
+			PrivateKey:     string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := p.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Version{{Name: "main", Revision: "a1", Moving: true}}; !slices.Equal(versions, want) {
+		t.Errorf("got %v, want %v", versions, want)
+	}
+
+	if got, want := p.GetPath("main", "api"), srv.URL+"/raw/o/r/a1/docs/api.json"; got != want {
+		t.Errorf("got path %q, want %q", got, want)
+	}
+
+	data, err := p.DownloadFile(context.Background(), "main", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"openapi":"3.0.0"}`; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+// etagResponse answers with 304 Not Modified if the request has the ETag, recording the If-None-Match header of each request.
+func etagResponse(etag, body string, ifNoneMatch *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*ifNoneMatch = append(*ifNoneMatch, r.Header.Get("If-None-Match"))
+
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		jsonResponse(body)(w, r)
+	}
+}
+
+func TestGithubConditionalRequests(t *testing.T) {
+	var branchesSent, treeSent []string
+	srv := newGithubStandIn(t, map[string]http.HandlerFunc{
+		"/api/v3/repos/o/r/branches":     etagResponse(`"branches"`, `[{"name":"main","commit":{"sha":"a1"}}]`, &branchesSent),
+		"/api/v3/repos/o/r/git/trees/a1": etagResponse(`"tree"`, `{"tree":[{"path":"api.json","type":"blob"}]}`, &treeSent),
+	})
+
+	p, err := NewGithub(&GithubConfig{
+		Owner:      "o",
+		Repo:       "r",
+		BaseURL:    srv.URL,
+		SkipTags:   true,
+		Branches:   []string{"main"},
+		FileSuffix: ".json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := p.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Version{{Name: "main", Revision: "a1", Moving: true}}; !slices.Equal(versions, want) {
+		t.Errorf("got versions %v, want %v", versions, want)
+	}
+
+	files, err := p.ListFiles(context.Background(), "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"api"}; !slices.Equal(files, want) {
+		t.Errorf("got files %v, want %v", files, want)
+	}
+
+	// Nothing changed, so the ETags are answered with 304 Not Modified
+	if _, err := p.ListVersions(context.Background()); !errors.Is(err, ErrNotModified) {
+		t.Errorf("got error %v, want %v", err, ErrNotModified)
+	}
+
+	cachedFiles, err := p.ListFiles(context.Background(), "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cachedFiles, files) {
+		t.Errorf("got files %v from the stored tree, want %v", cachedFiles, files)
+	}
+
+	if want := []string{"", `"branches"`}; !slices.Equal(branchesSent, want) {
+		t.Errorf("got If-None-Match %q for the branches, want %q", branchesSent, want)
+	}
+	if want := []string{"", `"tree"`}; !slices.Equal(treeSent, want) {
+		t.Errorf("got If-None-Match %q for the tree, want %q", treeSent, want)
+	}
+}
+
+func TestGithubCommitDatesSurviveRateLimit(t *testing.T) {
+	tags := `[
+		{"name":"v1.2.0","commit":{"sha":"c3"}},
+		{"name":"v1.1.0","commit":{"sha":"b2"}},
+		{"name":"v1.0.0","commit":{"sha":"a1"}}
+	]`
+	requests := make(map[string]int)
+	commit := func(sha, date string, rateLimited bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			requests[sha]++
+			if rateLimited && requests[sha] == 1 {
+				w.Header().Set("X-RateLimit-Limit", "60")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", fmt.Sprint(time.Now().Add(-time.Second).Unix()))
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `{"message":"API rate limit exceeded"}`)
+				return
+			}
+			jsonResponse(fmt.Sprintf(`{"committer":{"date":%q}}`, date))(w, r)
+		}
+	}
+	srv := newGithubStandIn(t, map[string]http.HandlerFunc{
+		"/api/v3/repos/o/r/tags": func(w http.ResponseWriter, r *http.Request) {
+			jsonResponse(tags)(w, r)
+		},
+		"/api/v3/repos/o/r/git/commits/c3": commit("c3", "2024-03-01T00:00:00Z", false),
+		"/api/v3/repos/o/r/git/commits/b2": commit("b2", "2024-02-01T00:00:00Z", true),
+		"/api/v3/repos/o/r/git/commits/a1": commit("a1", "2024-01-01T00:00:00Z", false),
+	})
+
+	p, err := NewGithub(&GithubConfig{Owner: "o", Repo: "r", BaseURL: srv.URL, TagOrder: TagOrderDate})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second commit is rate limited
+	var rateLimitErr RateLimitError
+	if _, err := p.ListVersions(context.Background()); !errors.As(err, &rateLimitErr) {
+		t.Fatalf("got error %v, want a rate limit error", err)
+	}
+
+	versions, err := p.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Version{{Name: "v1.2.0", Revision: "c3"}, {Name: "v1.1.0", Revision: "b2"}, {Name: "v1.0.0", Revision: "a1"}}
+	if !slices.Equal(versions, want) {
+		t.Errorf("got versions %v, want %v", versions, want)
+	}
+
+	// The date fetched before the rate limit is not requested again
+	if want := map[string]int{"c3": 1, "b2": 2, "a1": 1}; !maps.Equal(requests, want) {
+		t.Errorf("got requests %v, want %v", requests, want)
+	}
+
+	// The dates of the commits that are no longer tagged are dropped
+	tags = `[{"name":"v1.2.0","commit":{"sha":"c3"}}]`
+	if _, err := p.ListVersions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := slices.Collect(maps.Keys(p.commitDates)); !slices.Equal(got, []string{"c3"}) {
+		t.Errorf("got commit dates of %v, want only c3", got)
+	}
+}
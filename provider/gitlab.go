@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGitlabBaseURL = "https://gitlab.com"
+	gitlabTreePageSize   = 100
+)
+
+type GitlabProvider struct {
+	*forgeClient
+
+	cfg     *GitlabConfig
+	rootUrl string
+}
+
+type GitlabConfig struct {
+	// The url of the GitLab instance, defaults to https://gitlab.com
+	BaseURL string
+	// The full path of the project (e.g. group/subgroup/project) or its numeric ID
+	Project    string
+	PathPrefix string
+	FileSuffix string
+	MaxTags    int
+	AuthToken  string
+}
+
+func NewGitlab(cfg *GitlabConfig) (*GitlabProvider, error) {
+	if cfg.Project == "" {
+		return nil, fmt.Errorf("project cannot be empty")
+	}
+
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultGitlabBaseURL
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	cfg.Project = strings.Trim(cfg.Project, "/")
+
+	if cfg.PathPrefix != "" {
+		cfg.PathPrefix = strings.Trim(cfg.PathPrefix, "/")
+	}
+
+	if cfg.MaxTags <= 0 {
+		slog.Info("max tags not set, using default", "default", defaultMaxTags)
+		cfg.MaxTags = defaultMaxTags
+	}
+
+	baseUrl, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+
+	return &GitlabProvider{
+		forgeClient: &forgeClient{
+			client: &http.Client{Timeout: 30 * time.Second},
+			apiUrl: fmt.Sprint(baseUrl.String(), "/api/v4/projects/", url.PathEscape(cfg.Project)),
+			authorize: func(req *http.Request) {
+				if cfg.AuthToken != "" {
+					req.Header.Set("PRIVATE-TOKEN", cfg.AuthToken)
+				}
+			},
+		},
+		cfg:     cfg,
+		rootUrl: fmt.Sprint(baseUrl.String(), "/", cfg.Project),
+	}, nil
+}
+
+// Get the names of all tags in the repository
+func (p *GitlabProvider) ListVersions(ctx context.Context) ([]Version, error) {
+	query := url.Values{}
+	query.Set("per_page", strconv.Itoa(p.cfg.MaxTags))
+
+	var tags []struct {
+		Name   string `json:"name"`
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	if _, err := p.getJSON(ctx, "/repository/tags", query, &tags); err != nil {
+		return nil, err
+	}
+
+	var versions []Version
+	for _, tag := range tags {
+		versions = append(versions, Version{Name: tag.Name, Revision: tag.Commit.ID})
+	}
+
+	return versions, nil
+}
+
+func (p *GitlabProvider) ListFiles(ctx context.Context, version string) ([]string, error) {
+	query := url.Values{}
+	query.Set("ref", version)
+	query.Set("recursive", "true")
+	query.Set("per_page", strconv.Itoa(gitlabTreePageSize))
+	if p.cfg.PathPrefix != "" {
+		query.Set("path", p.cfg.PathPrefix)
+	}
+
+	var files []string
+	for page := "1"; page != ""; {
+		query.Set("page", page)
+
+		var entries []struct {
+			Type string `json:"type"`
+			Path string `json:"path"`
+		}
+		resp, err := p.getJSON(ctx, "/repository/tree", query, &entries)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			// Only include files that are in the path prefix and are blobs
+			if entry.Type != "blob" {
+				continue
+			}
+
+			if f, ok := treeFile(version, entry.Path, p.cfg.PathPrefix, p.cfg.FileSuffix); ok {
+				files = append(files, f)
+			}
+		}
+
+		page = resp.Header.Get("X-Next-Page")
+	}
+
+	return files, nil
+}
+
+func (p *GitlabProvider) GetPath(version, file string) string {
+	return fmt.Sprint(p.rootUrl, "/-/raw/", version, "/", escapePath(p.filePath(file)))
+}
+
+// RequiresProxy reports if the project is not public,
+// in which case the browser can not fetch the files from the links of GetPath.
+func (p *GitlabProvider) RequiresProxy(ctx context.Context) (bool, error) {
+	var project struct {
+		Visibility string `json:"visibility"`
+	}
+	if _, err := p.getJSON(ctx, "", nil, &project); err != nil {
+		return false, err
+	}
+
+	return project.Visibility != "public", nil
+}
+
+func (p *GitlabProvider) DownloadFile(ctx context.Context, version, file string) ([]byte, error) {
+	query := url.Values{}
+	query.Set("ref", version)
+
+	resp, err := p.get(ctx, "/repository/files/"+url.PathEscape(p.filePath(file))+"/raw", query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// filePath returns the path of a file relative to the root of the repository.
+func (p *GitlabProvider) filePath(file string) string {
+	return repoFilePath(p.cfg.PathPrefix, file, p.cfg.FileSuffix)
+}
@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newGitlabStandIn serves a project with two tags and a paginated tree.
+func newGitlabStandIn(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	const project = "/api/v4/projects/group%2Fproject"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path := r.URL.EscapedPath(); {
+		case path == project+"/repository/tags":
+			fmt.Fprint(w, `[{"name":"v1.1.0","commit":{"id":"bbb"}},{"name":"v1.0.0","commit":{"id":"aaa"}}]`)
+
+		case path == project+"/repository/tree":
+			if r.URL.Query().Get("ref") != "v1.0.0" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			if r.URL.Query().Get("page") == "1" {
+				w.Header().Set("X-Next-Page", "2")
+				fmt.Fprint(w, `[{"type":"tree","path":"api/nested"},{"type":"blob","path":"api/a.swagger.json"},{"type":"blob","path":"api/README.md"}]`)
+				return
+			}
+			fmt.Fprint(w, `[{"type":"blob","path":"api/nested/b.swagger.json"}]`)
+
+		case r.Header.Get("PRIVATE-TOKEN") != "token":
+			w.WriteHeader(http.StatusUnauthorized)
+
+		case path == project+"/repository/files/api%2Fa.swagger.json/raw":
+			fmt.Fprint(w, `{"swagger":"2.0"}`)
+
+		case path == project+"/repository/files/api%2Flimited.swagger.json/raw":
+			w.Header().Set("RateLimit-Limit", "600")
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newTestGitlab(t *testing.T) *GitlabProvider {
+	t.Helper()
+
+	srv := newGitlabStandIn(t)
+	p, err := NewGitlab(&GitlabConfig{
+		BaseURL:    srv.URL,
+		Project:    "group/project",
+		PathPrefix: "api/",
+		FileSuffix: ".swagger.json",
+		AuthToken:  "token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestGitlabListVersions(t *testing.T) {
+	p := newTestGitlab(t)
+
+	versions, err := p.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Version{{Name: "v1.1.0", Revision: "bbb"}, {Name: "v1.0.0", Revision: "aaa"}}
+	if !slices.Equal(versions, want) {
+		t.Errorf("got %v, want %v", versions, want)
+	}
+}
+
+func TestGitlabListFiles(t *testing.T) {
+	p := newTestGitlab(t)
+
+	tests := []struct {
+		name    string
+		version string
+		want    []string
+		wantErr error
+	}{
+		{name: "follows pages and filters suffix", version: "v1.0.0", want: []string{"a", "nested/b"}},
+		{name: "unknown ref", version: "v9.9.9", wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := p.ListFiles(context.Background(), tt.version)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if !slices.Equal(files, tt.want) {
+				t.Errorf("got %v, want %v", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitlabDownloadFile(t *testing.T) {
+	p := newTestGitlab(t)
+
+	tests := []struct {
+		name          string
+		file          string
+		want          string
+		wantErr       error
+		wantRateLimit bool
+	}{
+		{name: "found", file: "a", want: `{"swagger":"2.0"}`},
+		{name: "not found", file: "missing", wantErr: ErrNotFound},
+		{name: "rate limited", file: "limited", wantRateLimit: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := p.DownloadFile(context.Background(), "v1.0.0", tt.file)
+
+			if tt.wantRateLimit {
+				var rlErr RateLimitError
+				if !errors.As(err, &rlErr) {
+					t.Fatalf("got error %v, want a RateLimitError", err)
+				}
+				if rlErr.Limit != 600 || rlErr.Remaining != 0 || rlErr.RetryAfter != 30*time.Second {
+					t.Errorf("got %+v, want limit 600, remaining 0 and retry after 30s", rlErr)
+				}
+				if limit, ok := p.RateLimit(); !ok || limit.Limit != 600 {
+					t.Errorf("rate limit was not tracked, got %+v", limit)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if string(data) != tt.want {
+				t.Errorf("got %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitlabGetPath(t *testing.T) {
+	p, err := NewGitlab(&GitlabConfig{
+		BaseURL:    "https://gitlab.example.com/",
+		Project:    "group/project",
+		PathPrefix: "api",
+		FileSuffix: ".json",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "https://gitlab.example.com/group/project/-/raw/v1.0.0/api/a.json"
+	if got := p.GetPath("v1.0.0", "a"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,402 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestProvider serves the files listed in a manifest fetched over HTTP,
+// so that any build system can publish docs by writing a single JSON or YAML file.
+//
+// The manifest lists the versions and for each version the files and their urls:
+//
+//	versions:
+//	  - name: v1.0.0
+//	    files:
+//	      - name: service-a
+//	        url: https://example.com/specs/v1.0.0/service-a.yaml
+//	      - name: service-b
+//	        url: v1.0.0/service-b.yaml
+//
+// Relative urls are resolved against the url of the manifest.
+type ManifestProvider struct {
+	client *http.Client
+
+	cfg         *ManifestConfig
+	manifestUrl *url.URL
+
+	manifestLock sync.RWMutex
+	manifest     *manifest
+	// The validators of the last fetched manifest, sent to only download it if it changed
+	etag         string
+	lastModified string
+}
+
+type ManifestConfig struct {
+	// The url of the manifest
+	ManifestURL string
+	// Sent as a bearer token with the requests to the host of the manifest
+	BearerToken string
+	// Sent with basic auth with the requests to the host of the manifest, used if BearerToken is empty
+	Username string
+	Password string
+	// Send the credentials with the requests to all hosts, the manifest could otherwise leak them to any host
+	AuthAllOrigins bool
+	// A PEM file with the certificates of an internal certificate authority
+	CABundle string
+}
+
+type manifest struct {
+	Versions []manifestVersion `yaml:"versions"`
+}
+
+type manifestVersion struct {
+	Name string `yaml:"name"`
+	// Optional, the names and urls of the files are hashed if it is not set.
+	// Only the manifest is hashed, so files republished at the same url are not
+	// downloaded again unless the revision is set and changed along with them.
+	Revision string `yaml:"revision"`
+	// If the files of the version can change without the version being renamed, e.g. for a branch
+	Moving bool           `yaml:"moving"`
+	Files  []manifestFile `yaml:"files"`
+}
+
+type manifestFile struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+func NewManifest(cfg *ManifestConfig) (*ManifestProvider, error) {
+	if cfg.ManifestURL == "" {
+		return nil, fmt.Errorf("manifest url cannot be empty")
+	}
+
+	manifestUrl, err := url.Parse(cfg.ManifestURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest url: %w", err)
+	}
+
+	if manifestUrl.Scheme != "http" && manifestUrl.Scheme != "https" {
+		return nil, fmt.Errorf("manifest url must be http or https")
+	}
+
+	if cfg.BearerToken != "" && cfg.Username != "" {
+		return nil, fmt.Errorf("only one of bearer token and username can be set")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	if cfg.CABundle != "" {
+		transport, err := transportWithCABundle(cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = transport
+	}
+
+	return &ManifestProvider{
+		client:      client,
+		cfg:         cfg,
+		manifestUrl: manifestUrl,
+	}, nil
+}
+
+// ListVersions fetches the manifest and returns its versions.
+// ErrNotModified is returned if the manifest did not change since the last call.
+func (p *ManifestProvider) ListVersions(ctx context.Context) ([]Version, error) {
+	m, err := p.fetchManifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]Version, 0, len(m.Versions))
+	for _, v := range m.Versions {
+		revision := v.Revision
+		if revision == "" {
+			revision = v.hash()
+		}
+
+		versions = append(versions, Version{Name: v.Name, Revision: revision, Moving: v.Moving})
+	}
+
+	return versions, nil
+}
+
+func (p *ManifestProvider) ListFiles(ctx context.Context, version string) ([]string, error) {
+	v, err := p.version(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(v.Files))
+	for _, f := range v.Files {
+		files = append(files, f.Name)
+	}
+
+	return files, nil
+}
+
+// GetPath returns the url of the file from the last fetched manifest, it does not fetch the manifest.
+func (p *ManifestProvider) GetPath(version, file string) string {
+	m := p.fetchedManifest()
+	if m == nil {
+		return ""
+	}
+
+	v, err := m.version(version)
+	if err != nil {
+		return ""
+	}
+
+	u, err := p.fileUrl(v, file)
+	if err != nil {
+		slog.Warn("failed to get the url of the file", "version", version, "file", file, "error", err)
+		return ""
+	}
+
+	return u
+}
+
+// RequiresProxy reports if the files require authentication,
+// in which case the browser can not fetch them from the links of GetPath.
+func (p *ManifestProvider) RequiresProxy(ctx context.Context) (bool, error) {
+	return p.cfg.BearerToken != "" || p.cfg.Username != "", nil
+}
+
+func (p *ManifestProvider) DownloadFile(ctx context.Context, version, file string) ([]byte, error) {
+	v, err := p.version(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := p.fileUrl(v, file)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := p.newRequest(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := handleManifestResponse(resp); err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// fetchManifest downloads and parses the manifest, revalidating it with the validators of the last one.
+// ErrNotModified is returned if the server reports that it did not change.
+func (p *ManifestProvider) fetchManifest(ctx context.Context) (*manifest, error) {
+	req, err := p.newRequest(ctx, p.manifestUrl.String())
+	if err != nil {
+		return nil, err
+	}
+
+	p.manifestLock.RLock()
+	if p.manifest != nil {
+		if p.etag != "" {
+			req.Header.Set("If-None-Match", p.etag)
+		}
+		if p.lastModified != "" {
+			req.Header.Set("If-Modified-Since", p.lastModified)
+		}
+	}
+	p.manifestLock.RUnlock()
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
+
+	if err := handleManifestResponse(resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	m, err := parseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+
+	p.manifestLock.Lock()
+	p.manifest = m
+	p.etag = resp.Header.Get("ETag")
+	p.lastModified = resp.Header.Get("Last-Modified")
+	p.manifestLock.Unlock()
+
+	return m, nil
+}
+
+// parseManifest parses a JSON or YAML manifest, skipping invalid versions and files.
+// Since JSON is a subset of YAML, both are parsed by the YAML decoder.
+func parseManifest(data []byte) (*manifest, error) {
+	var m manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	versions := make([]manifestVersion, 0, len(m.Versions))
+	seenVersions := map[string]bool{}
+	for _, v := range m.Versions {
+		if v.Name == "" || strings.Contains(v.Name, "/") {
+			slog.Warn("invalid version name in manifest, skipping", "version", v.Name)
+			continue
+		}
+
+		if seenVersions[v.Name] {
+			slog.Warn("duplicate version in manifest, skipping", "version", v.Name)
+			continue
+		}
+		seenVersions[v.Name] = true
+
+		files := make([]manifestFile, 0, len(v.Files))
+		seenFiles := map[string]bool{}
+		for _, f := range v.Files {
+			if f.Name == "" || f.URL == "" {
+				slog.Warn("file without name or url in manifest, skipping", "version", v.Name, "file", f.Name)
+				continue
+			}
+
+			if seenFiles[f.Name] {
+				slog.Warn("duplicate file in manifest, skipping", "version", v.Name, "file", f.Name)
+				continue
+			}
+			seenFiles[f.Name] = true
+
+			files = append(files, f)
+		}
+		v.Files = files
+
+		versions = append(versions, v)
+	}
+	m.Versions = versions
+
+	return &m, nil
+}
+
+// fetchedManifest returns the last fetched manifest, nil if it has not been fetched yet.
+func (p *ManifestProvider) fetchedManifest() *manifest {
+	p.manifestLock.RLock()
+	defer p.manifestLock.RUnlock()
+
+	return p.manifest
+}
+
+// version returns a version of the last fetched manifest, fetching it if it has not been fetched yet.
+func (p *ManifestProvider) version(ctx context.Context, name string) (*manifestVersion, error) {
+	m := p.fetchedManifest()
+	if m == nil {
+		var err error
+		m, err = p.fetchManifest(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m.version(name)
+}
+
+func (m *manifest) version(name string) (*manifestVersion, error) {
+	for i := range m.Versions {
+		if m.Versions[i].Name == name {
+			return &m.Versions[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: version=%s", ErrNotFound, name)
+}
+
+// fileUrl returns the url of a file, resolved against the url of the manifest.
+func (p *ManifestProvider) fileUrl(v *manifestVersion, file string) (string, error) {
+	for _, f := range v.Files {
+		if f.Name != file {
+			continue
+		}
+
+		u, err := p.manifestUrl.Parse(f.URL)
+		if err != nil {
+			return "", fmt.Errorf("invalid url of file %s: %w", file, err)
+		}
+
+		return u.String(), nil
+	}
+
+	return "", fmt.Errorf("%w: file=%s", ErrNotFound, file)
+}
+
+// newRequest creates a GET request with the configured authentication.
+// The credentials are only sent to the origin of the manifest unless configured otherwise,
+// since the urls of the files are controlled by whoever publishes the manifest.
+func (p *ManifestProvider) newRequest(ctx context.Context, u string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.cfg.AuthAllOrigins && !p.sameOrigin(req.URL) {
+		return req, nil
+	}
+
+	if p.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.cfg.BearerToken)
+	} else if p.cfg.Username != "" {
+		req.SetBasicAuth(p.cfg.Username, p.cfg.Password)
+	}
+
+	return req, nil
+}
+
+// sameOrigin reports if the url has the same scheme and host as the manifest.
+func (p *ManifestProvider) sameOrigin(u *url.URL) bool {
+	return strings.EqualFold(u.Scheme, p.manifestUrl.Scheme) && strings.EqualFold(u.Host, p.manifestUrl.Host)
+}
+
+// hash returns a hash of the names and urls of the files of the version.
+func (v *manifestVersion) hash() string {
+	h := fnv.New64a()
+	for _, f := range v.Files {
+		fmt.Fprintf(h, "%s\x00%s\x00", f.Name, f.URL)
+	}
+
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+func handleManifestResponse(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, resp.Request.URL.Path)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return rateLimitErrorFromResponse(resp)
+	default:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
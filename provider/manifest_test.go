@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// authRecorder serves the routes and records the Authorization header sent for each path.
+type authRecorder struct {
+	lock sync.Mutex
+	auth map[string]string
+}
+
+func newAuthRecordingServer(t *testing.T, rec *authRecorder, routes map[string]string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.lock.Lock()
+		rec.auth[r.Host+r.URL.Path] = r.Header.Get("Authorization")
+		rec.lock.Unlock()
+
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestManifestAuthOrigins(t *testing.T) {
+	rec := &authRecorder{auth: make(map[string]string)}
+	other := newAuthRecordingServer(t, rec, map[string]string{"/b.json": `{"name":"b"}`})
+	manifestSrv := newAuthRecordingServer(t, rec, map[string]string{
+		"/a.json": `{"name":"a"}`,
+		"/manifest.yaml": fmt.Sprintf(`versions:
+  - name: v1.0.0
+    files:
+      - name: a
+        url: a.json
+      - name: b
+        url: %s/b.json
+`, other.URL),
+	})
+
+	tests := []struct {
+		name           string
+		authAllOrigins bool
+		wantOtherAuth  string
+	}{
+		{name: "same origin only", wantOtherAuth: ""},
+		{name: "all origins", authAllOrigins: true, wantOtherAuth: "Bearer secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewManifest(&ManifestConfig{
+				ManifestURL:    manifestSrv.URL + "/manifest.yaml",
+				BearerToken:    "secret",
+				AuthAllOrigins: tt.authAllOrigins,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, file := range []string{"a", "b"} {
+				if _, err := p.DownloadFile(context.Background(), "v1.0.0", file); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rec.lock.Lock()
+			defer rec.lock.Unlock()
+
+			manifestHost := manifestSrv.Listener.Addr().String()
+			for path, want := range map[string]string{
+				manifestHost + "/manifest.yaml":            "Bearer secret",
+				manifestHost + "/a.json":                   "Bearer secret",
+				other.Listener.Addr().String() + "/b.json": tt.wantOtherAuth,
+			} {
+				if got := rec.auth[path]; got != want {
+					t.Errorf("got authorization %q for %s, want %q", got, path, want)
+				}
+			}
+		})
+	}
+}
+
+func TestManifestGetPathDoesNotFetch(t *testing.T) {
+	rec := &authRecorder{auth: make(map[string]string)}
+	srv := newAuthRecordingServer(t, rec, map[string]string{
+		"/manifest.yaml": "versions:\n  - name: v1.0.0\n    files:\n      - name: a\n        url: specs/a.json\n",
+	})
+
+	p, err := NewManifest(&ManifestConfig{ManifestURL: srv.URL + "/manifest.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := p.GetPath("v1.0.0", "a"); got != "" {
+		t.Errorf("got path %q before the manifest was fetched, want none", got)
+	}
+	if len(rec.auth) != 0 {
+		t.Fatalf("GetPath made %d requests", len(rec.auth))
+	}
+
+	if _, err := p.ListVersions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		version string
+		file    string
+		want    string
+	}{
+		{version: "v1.0.0", file: "a", want: srv.URL + "/specs/a.json"},
+		{version: "v1.0.0", file: "b", want: ""},
+		{version: "v2.0.0", file: "a", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := p.GetPath(tt.version, tt.file); got != tt.want {
+			t.Errorf("got path %q for %s of %s, want %q", got, tt.file, tt.version, tt.want)
+		}
+	}
+}
+
+func TestManifestRevalidation(t *testing.T) {
+	var ifNoneMatch []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, "versions:\n  - name: v1.0.0\n    files:\n      - name: a\n        url: a.json\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	p, err := NewManifest(&ManifestConfig{ManifestURL: srv.URL + "/manifest.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := p.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].Name != "v1.0.0" {
+		t.Fatalf("got versions %v, want v1.0.0", versions)
+	}
+
+	if _, err := p.ListVersions(context.Background()); !errors.Is(err, ErrNotModified) {
+		t.Errorf("got error %v, want %v", err, ErrNotModified)
+	}
+	if want := []string{"", `"v1"`}; !slices.Equal(ifNoneMatch, want) {
+		t.Errorf("got If-None-Match %q, want %q", ifNoneMatch, want)
+	}
+
+	// The files are still known from the manifest fetched before
+	if got := p.GetPath("v1.0.0", "a"); got != srv.URL+"/a.json" {
+		t.Errorf("got path %q, want %q", got, srv.URL+"/a.json")
+	}
+}
@@ -0,0 +1,476 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/theleeeo/docs-server/semver"
+)
+
+const (
+	ociTitleAnnotation = "org.opencontainers.image.title"
+	ociTagsPageSize    = 100
+)
+
+// The manifest formats accepted from the registry, both list the layers the same way
+var ociManifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// OCIProvider serves files published as OCI artifacts, such as with oras push.
+// Each tag of the repository is a version and each layer of its manifest a file,
+// named by the org.opencontainers.image.title annotation of the layer.
+type OCIProvider struct {
+	rateLimitTracker
+
+	client *http.Client
+	auth   *ociAuth
+
+	cfg *OCIConfig
+	// The url of the repository in the registry API, e.g. https://registry.example.com/v2/team/docs
+	repoUrl string
+
+	manifestsLock sync.Mutex
+	// The digest of the manifest of each tag, as of the last call to ListVersions
+	tagDigests map[string]string
+	// The manifests of the tags by digest, they never change since the digest is the hash of the manifest
+	manifests map[string]*ociManifest
+}
+
+type OCIConfig struct {
+	// The url of the registry, e.g. https://registry.example.com
+	// The scheme defaults to https if not given
+	Registry string
+	// The repository in the registry, e.g. team/api-docs
+	Repository string
+	// The suffix of the layer titles, layers without it are skipped
+	FileSuffix string
+	MaxTags    int
+	// Used for basic auth or to request tokens, anonymous tokens are requested if empty
+	Username string
+	Password string
+	// A PEM file with the certificates of an internal certificate authority
+	CABundle string
+}
+
+type ociManifest struct {
+	MediaType string     `json:"mediaType"`
+	Layers    []ociLayer `json:"layers"`
+}
+
+type ociLayer struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+func NewOCI(cfg *OCIConfig) (*OCIProvider, error) {
+	if cfg.Registry == "" {
+		return nil, fmt.Errorf("registry cannot be empty")
+	}
+
+	if cfg.Repository == "" {
+		return nil, fmt.Errorf("repository cannot be empty")
+	}
+	cfg.Repository = strings.Trim(cfg.Repository, "/")
+
+	if !strings.Contains(cfg.Registry, "://") {
+		cfg.Registry = "https://" + cfg.Registry
+	}
+	cfg.Registry = strings.TrimRight(cfg.Registry, "/")
+
+	if _, err := url.Parse(cfg.Registry); err != nil {
+		return nil, fmt.Errorf("invalid registry: %w", err)
+	}
+
+	if cfg.MaxTags <= 0 {
+		slog.Info("max tags not set, using default", "default", defaultMaxTags)
+		cfg.MaxTags = defaultMaxTags
+	}
+
+	if cfg.Password != "" && cfg.Username == "" {
+		return nil, fmt.Errorf("username cannot be empty if a password is set")
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		// Blobs are often served from a storage that rejects the authorization of the registry
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			if req.URL.Host != via[0].URL.Host {
+				req.Header.Del("Authorization")
+			}
+			return nil
+		},
+	}
+	if cfg.CABundle != "" {
+		transport, err := transportWithCABundle(cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = transport
+	}
+
+	return &OCIProvider{
+		client: client,
+		auth: &ociAuth{
+			client:   client,
+			username: cfg.Username,
+			password: cfg.Password,
+			scope:    fmt.Sprintf("repository:%s:pull", cfg.Repository),
+		},
+		cfg:        cfg,
+		repoUrl:    fmt.Sprint(cfg.Registry, "/v2/", cfg.Repository),
+		tagDigests: make(map[string]string),
+		manifests:  make(map[string]*ociManifest),
+	}, nil
+}
+
+// ListVersions returns the newest tags of the repository, with the digest of their manifest as revision.
+// Semantic versions are ordered with the newest first, followed by the other tags.
+// The other tags, such as latest, can be moved to another manifest and are therefore marked as moving.
+func (p *OCIProvider) ListVersions(ctx context.Context) ([]Version, error) {
+	tags, err := p.listTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(tags, semver.CompareNamesNewestFirst)
+	if len(tags) > p.cfg.MaxTags {
+		tags = tags[:p.cfg.MaxTags]
+	}
+
+	tagDigests := make(map[string]string, len(tags))
+	versions := make([]Version, 0, len(tags))
+	for _, tag := range tags {
+		digest, err := p.manifestDigest(ctx, tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get digest of tag %s: %w", tag, err)
+		}
+		tagDigests[tag] = digest
+
+		_, isSemver := semver.Parse(tag)
+		versions = append(versions, Version{Name: tag, Revision: digest, Moving: !isSemver})
+	}
+
+	p.manifestsLock.Lock()
+	p.tagDigests = tagDigests
+	// Forget the manifests no tag points to anymore
+	current := make(map[string]bool, len(tagDigests))
+	for _, digest := range tagDigests {
+		current[digest] = true
+	}
+	for digest := range p.manifests {
+		if !current[digest] {
+			delete(p.manifests, digest)
+		}
+	}
+	p.manifestsLock.Unlock()
+
+	return versions, nil
+}
+
+func (p *OCIProvider) ListFiles(ctx context.Context, version string) ([]string, error) {
+	m, err := p.manifest(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, layer := range m.Layers {
+		title := layer.Annotations[ociTitleAnnotation]
+		if title == "" {
+			slog.Warn("layer without title, skipping", "digest", layer.Digest, "version", version)
+			continue
+		}
+
+		// If the file does not end with the suffix, skip it
+		f, ok := strings.CutSuffix(title, p.cfg.FileSuffix)
+		if !ok {
+			slog.Warn("file does not end with the suffix, skipping", "file", title, "version", version, "suffix", p.cfg.FileSuffix)
+			continue
+		}
+
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// GetPath returns an empty path since pulling from the registry requires a token the browser does not have.
+// The OCI provider must be served through the proxy.
+func (p *OCIProvider) GetPath(version, file string) string {
+	return ""
+}
+
+// DownloadFile pulls the layer of the file and verifies its digest.
+func (p *OCIProvider) DownloadFile(ctx context.Context, version, file string) ([]byte, error) {
+	m, err := p.manifest(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	title := file + p.cfg.FileSuffix
+	i := slices.IndexFunc(m.Layers, func(l ociLayer) bool {
+		return l.Annotations[ociTitleAnnotation] == title
+	})
+	if i < 0 {
+		return nil, fmt.Errorf("%w: file=%s", ErrNotFound, file)
+	}
+	layer := m.Layers[i]
+
+	resp, err := p.get(ctx, p.repoUrl+"/blobs/"+layer.Digest, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyDigest(layer.Digest, data); err != nil {
+		return nil, fmt.Errorf("failed to verify file %s: %w", file, err)
+	}
+
+	return data, nil
+}
+
+// listTags lists all tags of the repository, following the pagination links.
+func (p *OCIProvider) listTags(ctx context.Context) ([]string, error) {
+	var tags []string
+
+	u := fmt.Sprintf("%s/tags/list?n=%d", p.repoUrl, ociTagsPageSize)
+	for u != "" {
+		resp, err := p.get(ctx, u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		tags = append(tags, page.Tags...)
+
+		u, err = nextLink(resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+// nextLink returns the absolute url of the next page from the Link header, or an empty string if there is none.
+func nextLink(resp *http.Response) (string, error) {
+	for _, link := range resp.Header.Values("Link") {
+		target, params, _ := strings.Cut(link, ";")
+		if !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+
+		next, err := resp.Request.URL.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return "", fmt.Errorf("invalid next link: %w", err)
+		}
+
+		return next.String(), nil
+	}
+
+	return "", nil
+}
+
+// manifestDigest returns the digest of the manifest of a tag without downloading the manifest.
+func (p *OCIProvider) manifestDigest(ctx context.Context, tag string) (string, error) {
+	resp, err := p.do(ctx, http.MethodHead, p.repoUrl+"/manifests/"+url.PathEscape(tag), ociManifestHeaders())
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// Registries are not required to send the digest, in which case it is calculated from the manifest
+	_, digest, err := p.fetchManifest(ctx, tag)
+	return digest, err
+}
+
+// manifest returns the manifest of a tag, from the cache if the tag has not moved since the last call to ListVersions.
+func (p *OCIProvider) manifest(ctx context.Context, tag string) (*ociManifest, error) {
+	p.manifestsLock.Lock()
+	digest, ok := p.tagDigests[tag]
+	m := p.manifests[digest]
+	p.manifestsLock.Unlock()
+
+	if m != nil {
+		return m, nil
+	}
+
+	// A known digest is pulled rather than the tag, so that the files match the revision of the version
+	ref := tag
+	if ok {
+		ref = digest
+	}
+
+	m, digest, err := p.fetchManifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	p.manifestsLock.Lock()
+	p.manifests[digest] = m
+	p.manifestsLock.Unlock()
+
+	return m, nil
+}
+
+// fetchManifest downloads the manifest of a tag or digest and returns it with its digest.
+func (p *OCIProvider) fetchManifest(ctx context.Context, ref string) (*ociManifest, string, error) {
+	resp, err := p.get(ctx, p.repoUrl+"/manifests/"+url.PathEscape(ref), ociManifestHeaders())
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var m ociManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, "", fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	mediaType := m.MediaType
+	if mediaType == "" {
+		mediaType, _, _ = strings.Cut(resp.Header.Get("Content-Type"), ";")
+	}
+	if !slices.Contains(ociManifestMediaTypes, mediaType) {
+		return nil, "", fmt.Errorf("unsupported manifest type %q of %s", mediaType, ref)
+	}
+
+	sum := sha256.Sum256(data)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if strings.HasPrefix(ref, "sha256:") && ref != digest {
+		return nil, "", fmt.Errorf("manifest does not match digest %s", ref)
+	}
+
+	return &m, digest, nil
+}
+
+func ociManifestHeaders() http.Header {
+	return http.Header{"Accept": {strings.Join(ociManifestMediaTypes, ", ")}}
+}
+
+// verifyDigest checks the data against a sha256 digest, other algorithms are not verified.
+func verifyDigest(digest string, data []byte) error {
+	algorithm, expected, ok := strings.Cut(digest, ":")
+	if !ok {
+		return fmt.Errorf("invalid digest: %s", digest)
+	}
+
+	if algorithm != "sha256" {
+		return nil
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != expected {
+		return fmt.Errorf("digest mismatch, expected %s", digest)
+	}
+
+	return nil
+}
+
+// get performs a GET request against the registry.
+// The caller is responsible for closing the body of the response.
+func (p *OCIProvider) get(ctx context.Context, u string, header http.Header) (*http.Response, error) {
+	return p.do(ctx, http.MethodGet, u, header)
+}
+
+// do performs an authenticated request against the registry, answering the authentication challenge if asked for one.
+// Redirects to the storage of blobs are followed without the authorization header.
+func (p *OCIProvider) do(ctx context.Context, method, u string, header http.Header) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		p.auth.authorize(req)
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if limit, ok := rateLimitFromHeaders(resp.Header); ok {
+			p.track(limit)
+		}
+
+		// Only one new challenge is answered per request, a second refusal means the credentials are not allowed
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			resp.Body.Close()
+			if err := p.auth.handleChallenge(ctx, resp); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := handleOCIResponse(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+
+		return resp, nil
+	}
+}
+
+func handleOCIResponse(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, resp.Request.URL.Path)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return rateLimitErrorFromResponse(resp)
+	default:
+		var registryErr struct {
+			Errors []struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&registryErr); err == nil && len(registryErr.Errors) > 0 {
+			e := registryErr.Errors[0]
+			return fmt.Errorf("unexpected status code: %d: %s: %s", resp.StatusCode, e.Code, e.Message)
+		}
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
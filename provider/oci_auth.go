@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Used when the token server does not say how long a token is valid, as specified by the distribution spec
+	defaultOCITokenLifetime = time.Minute
+	// Tokens are refreshed this long before they expire
+	ociTokenRefreshMargin = 10 * time.Second
+)
+
+// ociAuth authenticates requests to a registry as requested by its challenges,
+// either with basic auth or with a bearer token fetched from the token server of the registry.
+type ociAuth struct {
+	client   *http.Client
+	username string
+	password string
+	// The scope requested if the challenge does not contain one, e.g. repository:team/docs:pull
+	scope string
+
+	lock sync.Mutex
+	// The scheme of the last challenge, empty until the registry has asked for authentication
+	scheme  string
+	token   string
+	expires time.Time
+}
+
+// authorize adds the authorization header to a request, if the registry has asked for authentication.
+func (a *ociAuth) authorize(req *http.Request) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	switch a.scheme {
+	case "basic":
+		req.SetBasicAuth(a.username, a.password)
+	case "bearer":
+		if a.token != "" && time.Until(a.expires) > ociTokenRefreshMargin {
+			req.Header.Set("Authorization", "Bearer "+a.token)
+		}
+	}
+}
+
+// handleChallenge answers the WWW-Authenticate challenge of a response with status 401.
+// For bearer challenges a new token is fetched from the token server.
+func (a *ociAuth) handleChallenge(ctx context.Context, resp *http.Response) error {
+	scheme, params := parseChallenge(resp.Header.Get("WWW-Authenticate"))
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	switch scheme {
+	case "basic":
+		if a.username == "" {
+			return fmt.Errorf("the registry requires credentials")
+		}
+		a.scheme = scheme
+		return nil
+	case "bearer":
+		token, expires, err := a.fetchToken(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to fetch registry token: %w", err)
+		}
+		a.scheme = scheme
+		a.token = token
+		a.expires = expires
+		return nil
+	default:
+		return fmt.Errorf("unsupported authentication challenge: %q", resp.Header.Get("WWW-Authenticate"))
+	}
+}
+
+// fetchToken requests a token from the token server given in a bearer challenge.
+func (a *ociAuth) fetchToken(ctx context.Context, params map[string]string) (string, time.Time, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return "", time.Time{}, fmt.Errorf("invalid realm: %q", params["realm"])
+	}
+
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if scope := params["scope"]; scope != "" {
+		query.Set("scope", scope)
+	} else {
+		query.Set("scope", a.scope)
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	// Without credentials an anonymous token is requested, which is enough for public repositories
+	if a.username != "" {
+		req.SetBasicAuth(a.username, a.password)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		IssuedAt    string `json:"issued_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to decode token: %w", err)
+	}
+
+	token := body.Token
+	if token == "" {
+		token = body.AccessToken
+	}
+	if token == "" {
+		return "", time.Time{}, fmt.Errorf("no token in response")
+	}
+
+	lifetime := defaultOCITokenLifetime
+	if body.ExpiresIn > 0 {
+		lifetime = time.Duration(body.ExpiresIn) * time.Second
+	}
+
+	issued := time.Now()
+	if t, err := time.Parse(time.RFC3339, body.IssuedAt); err == nil && t.Before(issued) {
+		issued = t
+	}
+
+	return token, issued.Add(lifetime), nil
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// Bearer realm="https://auth.example.com/token",service="registry",scope="repository:docs:pull".
+// The scheme is returned in lower case.
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimLeft(rest, ", ") {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		if strings.HasPrefix(value, `"`) {
+			// Quoted values can contain commas, such as scopes with several actions
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+			params[key] = strings.TrimSpace(value)
+		}
+	}
+
+	return strings.ToLower(scheme), params
+}
@@ -0,0 +1,325 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantScheme string
+		wantParams map[string]string
+	}{
+		{
+			name:       "bearer with a scope of several actions",
+			header:     `Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:team/docs:pull,push"`,
+			wantScheme: "bearer",
+			wantParams: map[string]string{
+				"realm":   "https://auth.example.com/token",
+				"service": "registry.example.com",
+				"scope":   "repository:team/docs:pull,push",
+			},
+		},
+		{
+			name:       "basic",
+			header:     `Basic realm="Registry Realm"`,
+			wantScheme: "basic",
+			wantParams: map[string]string{"realm": "Registry Realm"},
+		},
+		{
+			name:       "unquoted values and spaces",
+			header:     `BEARER Realm=https://auth.example.com/token, service=registry`,
+			wantScheme: "bearer",
+			wantParams: map[string]string{"realm": "https://auth.example.com/token", "service": "registry"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme, params := parseChallenge(tt.header)
+			if scheme != tt.wantScheme {
+				t.Errorf("got scheme %q, want %q", scheme, tt.wantScheme)
+			}
+			if !maps.Equal(params, tt.wantParams) {
+				t.Errorf("got params %v, want %v", params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []string
+		want  string
+	}{
+		{name: "none"},
+		{
+			name:  "relative",
+			links: []string{`</v2/team/docs/tags/list?n=100&last=v1.0.0>; rel="next"`},
+			want:  "https://registry.example.com/v2/team/docs/tags/list?n=100&last=v1.0.0",
+		},
+		{
+			name:  "absolute on another host",
+			links: []string{`<https://mirror.example.com/v2/team/docs/tags/list?last=v1.0.0>; rel="next"`},
+			want:  "https://mirror.example.com/v2/team/docs/tags/list?last=v1.0.0",
+		},
+		{
+			name:  "not the next page",
+			links: []string{`</v2/team/docs/tags/list>; rel="first"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				Header:  http.Header{"Link": tt.links},
+				Request: &http.Request{URL: &url.URL{Scheme: "https", Host: "registry.example.com", Path: "/v2/team/docs/tags/list"}},
+			}
+
+			got, err := nextLink(resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyDigest(t *testing.T) {
+	data := []byte(`{"openapi":"3.0.0"}`)
+
+	tests := []struct {
+		name    string
+		digest  string
+		wantErr bool
+	}{
+		{name: "matching", digest: sha256Digest(data)},
+		{name: "mismatch", digest: sha256Digest([]byte("other")), wantErr: true},
+		{name: "other algorithm is not verified", digest: "sha512:abc"},
+		{name: "invalid", digest: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyDigest(tt.digest, data); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func sha256Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ociStandIn is a registry requiring a bearer token from its token server,
+// listing the tags one per page.
+type ociStandIn struct {
+	*httptest.Server
+
+	tags      []string
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	// The number of tokens handed out
+	tokens atomic.Int32
+}
+
+func newOCIStandIn(t *testing.T) *ociStandIn {
+	t.Helper()
+
+	s := &ociStandIn{manifests: make(map[string][]byte), blobs: make(map[string][]byte)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// push adds a tag with a layer per file, the served blobs can differ from the files to corrupt them.
+func (s *ociStandIn) push(tag string, files map[string]string, served map[string]string) {
+	var layers []ociLayer
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		digest := sha256Digest([]byte(files[name]))
+		layers = append(layers, ociLayer{
+			MediaType:   "application/json",
+			Digest:      digest,
+			Size:        int64(len(files[name])),
+			Annotations: map[string]string{ociTitleAnnotation: name},
+		})
+
+		blob, ok := served[name]
+		if !ok {
+			blob = files[name]
+		}
+		s.blobs[digest] = []byte(blob)
+	}
+
+	m, _ := json.Marshal(ociManifest{MediaType: ociManifestMediaTypes[0], Layers: layers})
+	s.manifests[tag] = m
+	s.manifests[sha256Digest(m)] = m
+	s.tags = append(s.tags, tag)
+}
+
+func (s *ociStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		if user, pass, _ := r.BasicAuth(); user != "user" || pass != "pass" || r.URL.Query().Get("scope") != "repository:team/docs:pull" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.tokens.Add(1)
+		fmt.Fprint(w, `{"token":"registry-token","expires_in":300}`)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer registry-token" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry"`, s.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"errors":[{"code":"UNAUTHORIZED","message":"authentication required"}]}`)
+		return
+	}
+
+	rest, ok := strings.CutPrefix(r.URL.Path, "/v2/team/docs/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case rest == "tags/list":
+		i := 0
+		if last := r.URL.Query().Get("last"); last != "" {
+			i = slices.Index(s.tags, last) + 1
+		}
+		if i+1 < len(s.tags) {
+			w.Header().Set("Link", fmt.Sprintf(`</v2/team/docs/tags/list?n=1&last=%s>; rel="next"`, s.tags[i]))
+		}
+		json.NewEncoder(w).Encode(map[string][]string{"tags": s.tags[i : i+1]})
+	case strings.HasPrefix(rest, "manifests/"):
+		m, ok := s.manifests[strings.TrimPrefix(rest, "manifests/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", sha256Digest(m))
+		w.Header().Set("Content-Type", ociManifestMediaTypes[0])
+		if r.Method == http.MethodGet {
+			w.Write(m)
+		}
+	case strings.HasPrefix(rest, "blobs/"):
+		blob, ok := s.blobs[strings.TrimPrefix(rest, "blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(blob)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestOCIProvider(t *testing.T) {
+	registry := newOCIStandIn(t)
+	registry.push("v1.0.0", map[string]string{"a.json": `{"name":"a"}`, "README.md": "readme"}, nil)
+	registry.push("latest", map[string]string{"a.json": `{"name":"latest"}`}, nil)
+	registry.push("v1.1.0", map[string]string{"a.json": `{"name":"a"}`, "b.json": `{"name":"b"}`}, map[string]string{"b.json": `{"name":"tampered"}`})
+
+	p, err := NewOCI(&OCIConfig{
+		Registry:   registry.URL,
+		Repository: "/team/docs/",
+		FileSuffix: ".json",
+		Username:   "user",
+		Password:   "pass",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := p.ListVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Version{
+		{Name: "v1.1.0", Revision: sha256Digest(registry.manifests["v1.1.0"])},
+		{Name: "v1.0.0", Revision: sha256Digest(registry.manifests["v1.0.0"])},
+		{Name: "latest", Revision: sha256Digest(registry.manifests["latest"]), Moving: true},
+	}
+	if !slices.Equal(versions, want) {
+		t.Errorf("got versions %v, want %v", versions, want)
+	}
+
+	t.Run("list files", func(t *testing.T) {
+		files, err := p.ListFiles(context.Background(), "v1.0.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"a"}; !slices.Equal(files, want) {
+			t.Errorf("got %v, want %v", files, want)
+		}
+	})
+
+	t.Run("download file", func(t *testing.T) {
+		tests := []struct {
+			version string
+			file    string
+			want    string
+			wantErr string
+		}{
+			{version: "v1.0.0", file: "a", want: `{"name":"a"}`},
+			{version: "latest", file: "a", want: `{"name":"latest"}`},
+			{version: "v1.1.0", file: "b", wantErr: "digest mismatch"},
+			{version: "v1.0.0", file: "b", wantErr: ErrNotFound.Error()},
+		}
+
+		for _, tt := range tests {
+			data, err := p.DownloadFile(context.Background(), tt.version, tt.file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v for %s of %s, want %q", err, tt.file, tt.version, tt.wantErr)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got %q for %s of %s, want %q", data, tt.file, tt.version, tt.want)
+			}
+		}
+	})
+
+	// The token is reused until it expires
+	if n := registry.tokens.Load(); n != 1 {
+		t.Errorf("got %d tokens, want 1", n)
+	}
+}
+
+func TestOCIProviderWrongCredentials(t *testing.T) {
+	registry := newOCIStandIn(t)
+	registry.push("v1.0.0", map[string]string{"a.json": "{}"}, nil)
+
+	p, err := NewOCI(&OCIConfig{Registry: registry.URL, Repository: "team/docs", Username: "user", Password: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.ListVersions(context.Background()); err == nil {
+		t.Error("expected an error when the token server refuses the credentials")
+	}
+}
@@ -0,0 +1,144 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is the request budget of a provider as last reported by it.
+type RateLimit struct {
+	// The maximum number of requests in the current window
+	Limit int `json:"limit"`
+	// The number of requests left in the current window
+	Remaining int `json:"remaining"`
+	// When the current window resets, zero if unknown
+	Reset time.Time `json:"reset"`
+}
+
+// RateLimitError is returned when the provider refuses a request because of a rate limit.
+// Use errors.As to inspect it.
+type RateLimitError struct {
+	Message string
+	// The maximum number of requests in the current window, 0 if unknown
+	Limit int
+	// The number of requests left in the current window
+	Remaining int
+	// When requests can be made again, zero if unknown
+	Reset time.Time
+	// How long the provider asked to wait before retrying, 0 if not given
+	RetryAfter time.Duration
+}
+
+// newRateLimitError creates a RateLimitError.
+// If the provider only gave a retry-after duration, the reset time is derived from it.
+func newRateLimitError(message string, limit RateLimit, retryAfter time.Duration) RateLimitError {
+	reset := limit.Reset
+	if retryAt := time.Now().Add(retryAfter); retryAfter > 0 && retryAt.After(reset) {
+		reset = retryAt
+	}
+
+	return RateLimitError{
+		Message:    message,
+		Limit:      limit.Limit,
+		Remaining:  limit.Remaining,
+		Reset:      reset,
+		RetryAfter: retryAfter,
+	}
+}
+
+func (r RateLimitError) Error() string {
+	var b strings.Builder
+	b.WriteString(r.Message)
+
+	if r.Limit > 0 {
+		fmt.Fprintf(&b, " limit=%d remaining=%d", r.Limit, r.Remaining)
+	}
+	if !r.Reset.IsZero() {
+		fmt.Fprintf(&b, " reset=%s", r.Reset.Format(time.RFC3339))
+	}
+	if r.RetryAfter > 0 {
+		fmt.Fprintf(&b, " retry_after=%s", r.RetryAfter)
+	}
+
+	return b.String()
+}
+
+// RetryAt returns when requests can be made again, if it is known.
+func (r RateLimitError) RetryAt() (time.Time, bool) {
+	return r.Reset, !r.Reset.IsZero()
+}
+
+// rateLimitTracker keeps the last rate limit reported by a provider.
+// It is embedded in the providers that report their rate limit.
+type rateLimitTracker struct {
+	lock      sync.Mutex
+	rateLimit *RateLimit
+}
+
+func (t *rateLimitTracker) track(limit RateLimit) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.rateLimit = &limit
+}
+
+// RateLimit returns the last rate limit reported by the provider, or false if it has not reported one.
+func (t *rateLimitTracker) RateLimit() (RateLimit, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.rateLimit == nil {
+		return RateLimit{}, false
+	}
+
+	return *t.rateLimit, true
+}
+
+// rateLimitFromHeaders reads the rate limit from the RateLimit-* or X-RateLimit-* headers of a response.
+func rateLimitFromHeaders(h http.Header) (RateLimit, bool) {
+	for _, prefix := range []string{"RateLimit-", "X-RateLimit-"} {
+		// Some registries add the window after the value, e.g. 100;w=21600
+		limitValue, _, _ := strings.Cut(h.Get(prefix+"Limit"), ";")
+		limit, err := strconv.Atoi(limitValue)
+		if err != nil {
+			continue
+		}
+
+		remainingValue, _, _ := strings.Cut(h.Get(prefix+"Remaining"), ";")
+		remaining, _ := strconv.Atoi(remainingValue)
+		rl := RateLimit{Limit: limit, Remaining: remaining}
+
+		if t, err := http.ParseTime(h.Get(prefix + "ResetTime")); err == nil {
+			rl.Reset = t
+		} else if sec, err := strconv.ParseInt(h.Get(prefix+"Reset"), 10, 64); err == nil {
+			rl.Reset = time.Unix(sec, 0)
+		}
+
+		return rl, true
+	}
+
+	return RateLimit{}, false
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as a date.
+func parseRetryAfter(value string) time.Duration {
+	if sec, err := strconv.Atoi(value); err == nil {
+		return time.Duration(sec) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0)
+	}
+
+	return 0
+}
+
+// rateLimitErrorFromResponse creates a RateLimitError from a response refused because of a rate limit.
+func rateLimitErrorFromResponse(resp *http.Response) RateLimitError {
+	limit, _ := rateLimitFromHeaders(resp.Header)
+	return newRateLimitError("rate limit reached", limit, parseRetryAfter(resp.Header.Get("Retry-After")))
+}
@@ -0,0 +1,320 @@
+package provider
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultS3Region        = "us-east-1"
+	defaultS3PresignExpiry = time.Hour
+	// The longest a presigned url can be valid
+	maxS3PresignExpiry = 7 * 24 * time.Hour
+)
+
+// S3Provider serves files from an S3 compatible object storage, such as AWS S3 or MinIO.
+// Each prefix directly under the configured prefix is a version, e.g. specs/{version}/{file}.
+type S3Provider struct {
+	client *http.Client
+
+	cfg *S3Config
+	// The url of the bucket, without a trailing slash
+	bucketUrl *url.URL
+	// Nil if the bucket is accessed anonymously
+	signer *sigV4Signer
+}
+
+type S3Config struct {
+	// The url of the S3 compatible service, e.g. https://minio.example.com:9000
+	// Defaults to https://s3.{region}.amazonaws.com
+	Endpoint string
+	// Defaults to us-east-1
+	Region string
+	Bucket string
+	// The prefix containing the versions, e.g. specs
+	Prefix     string
+	FileSuffix string
+	// The bucket is accessed anonymously if the credentials are empty
+	AccessKeyID     string
+	SecretAccessKey string
+	// Address the bucket in the path instead of in the host name, as required by MinIO by default
+	PathStyle bool
+	// How long the links to the files are valid, defaults to an hour
+	PresignExpiry time.Duration
+}
+
+func NewS3(cfg *S3Config) (*S3Provider, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("bucket cannot be empty")
+	}
+
+	if cfg.Region == "" {
+		slog.Info("region not set, using default", "default", defaultS3Region)
+		cfg.Region = defaultS3Region
+	}
+
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+
+	if cfg.Prefix != "" {
+		cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+	}
+
+	if (cfg.AccessKeyID == "") != (cfg.SecretAccessKey == "") {
+		return nil, fmt.Errorf("both access key id and secret access key must be set")
+	}
+
+	if cfg.PresignExpiry <= 0 {
+		cfg.PresignExpiry = defaultS3PresignExpiry
+	}
+	if cfg.PresignExpiry > maxS3PresignExpiry {
+		return nil, fmt.Errorf("presign expiry cannot be longer than %s", maxS3PresignExpiry)
+	}
+
+	bucketUrl, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	if cfg.PathStyle {
+		bucketUrl = bucketUrl.JoinPath(cfg.Bucket)
+	} else {
+		bucketUrl.Host = cfg.Bucket + "." + bucketUrl.Host
+	}
+
+	p := &S3Provider{
+		client:    &http.Client{Timeout: 30 * time.Second},
+		cfg:       cfg,
+		bucketUrl: bucketUrl,
+	}
+
+	if cfg.AccessKeyID != "" {
+		p.signer = &sigV4Signer{
+			accessKeyID:     cfg.AccessKeyID,
+			secretAccessKey: cfg.SecretAccessKey,
+			region:          cfg.Region,
+		}
+	}
+
+	return p, nil
+}
+
+// ListVersions returns the prefixes directly under the configured prefix.
+// The revision of a version is a hash of the keys, ETags and modification times of its objects.
+func (p *S3Provider) ListVersions(ctx context.Context) ([]Version, error) {
+	objects, err := p.listObjects(ctx, p.keyPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	hashes := make(map[string]hash.Hash64)
+	for _, object := range objects {
+		name, _, ok := strings.Cut(strings.TrimPrefix(object.Key, p.keyPrefix()), "/")
+		if !ok || name == "" {
+			continue
+		}
+
+		h, ok := hashes[name]
+		if !ok {
+			h = fnv.New64a()
+			hashes[name] = h
+			names = append(names, name)
+		}
+
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", object.Key, object.ETag, object.LastModified)
+	}
+
+	versions := make([]Version, 0, len(names))
+	for _, name := range names {
+		// The objects can be overwritten at any time
+		versions = append(versions, Version{Name: name, Revision: strconv.FormatUint(hashes[name].Sum64(), 16), Moving: true})
+	}
+
+	return versions, nil
+}
+
+func (p *S3Provider) ListFiles(ctx context.Context, version string) ([]string, error) {
+	versionPrefix := p.keyPrefix() + version + "/"
+
+	objects, err := p.listObjects(ctx, versionPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("%w: version=%s", ErrNotFound, version)
+	}
+
+	var files []string
+	for _, object := range objects {
+		f := strings.TrimPrefix(object.Key, versionPrefix)
+
+		// Empty objects ending with a slash are used as folders by some tools
+		if f == "" || strings.HasSuffix(f, "/") {
+			continue
+		}
+
+		// If the file does not end with the suffix, skip it
+		f, ok := strings.CutSuffix(f, p.cfg.FileSuffix)
+		if !ok {
+			slog.Warn("file does not end with the suffix, skipping", "file", f, "version", version, "suffix", p.cfg.FileSuffix)
+			continue
+		}
+
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// GetPath returns a presigned url of the file, or a plain url if the bucket is accessed anonymously.
+func (p *S3Provider) GetPath(version, file string) string {
+	u := p.objectUrl(p.fileKey(version, file))
+	if p.signer == nil {
+		return u.String()
+	}
+
+	return p.signer.presign(u, p.cfg.PresignExpiry, time.Now())
+}
+
+func (p *S3Provider) DownloadFile(ctx context.Context, version, file string) ([]byte, error) {
+	resp, err := p.get(ctx, p.objectUrl(p.fileKey(version, file)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// keyPrefix returns the prefix of the keys of all versions, empty or ending with a slash.
+func (p *S3Provider) keyPrefix() string {
+	if p.cfg.Prefix == "" {
+		return ""
+	}
+
+	return p.cfg.Prefix + "/"
+}
+
+func (p *S3Provider) fileKey(version, file string) string {
+	return fmt.Sprint(p.keyPrefix(), version, "/", file, p.cfg.FileSuffix)
+}
+
+// objectUrl returns the url of an object.
+// The path is encoded the same way as when it is signed.
+func (p *S3Provider) objectUrl(key string) *url.URL {
+	u := *p.bucketUrl
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + key
+	u.RawPath = canonicalURI(&u)
+
+	return &u
+}
+
+// s3Object is an object returned by a listing.
+type s3Object struct {
+	Key          string `xml:"Key"`
+	ETag         string `xml:"ETag"`
+	LastModified string `xml:"LastModified"`
+}
+
+// listObjects lists the objects with a key starting with the prefix, following the continuation tokens.
+func (p *S3Provider) listObjects(ctx context.Context, prefix string) ([]s3Object, error) {
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("prefix", prefix)
+
+	var objects []s3Object
+	for {
+		u := p.objectUrl("")
+		u.RawQuery = canonicalQuery(query)
+
+		var result struct {
+			IsTruncated           bool       `xml:"IsTruncated"`
+			NextContinuationToken string     `xml:"NextContinuationToken"`
+			Contents              []s3Object `xml:"Contents"`
+		}
+		if err := p.getXML(ctx, u, &result); err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, result.Contents...)
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+func (p *S3Provider) getXML(ctx context.Context, u *url.URL, v any) error {
+	resp, err := p.get(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := xml.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// get performs a signed GET request.
+// The caller is responsible for closing the body of the response.
+func (p *S3Provider) get(ctx context.Context, u *url.URL) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.signer != nil {
+		p.signer.sign(req, time.Now())
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := handleS3Response(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func handleS3Response(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, resp.Request.URL.Path)
+	// S3 asks clients to slow down with 503 SlowDown
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		return rateLimitErrorFromResponse(resp)
+	default:
+		var s3Err struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		if err := xml.NewDecoder(resp.Body).Decode(&s3Err); err == nil && s3Err.Code != "" {
+			return fmt.Errorf("unexpected status code: %d: %s: %s", resp.StatusCode, s3Err.Code, s3Err.Message)
+		}
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
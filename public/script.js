@@ -1,9 +1,12 @@
+// The path that all urls of the current project start with
+const basePath = document.getElementById('document-content').dataset.basePath;
+
 window.onload = function () {
     setupVersionSelect();
 };
 
 function setupVersionSelect() {
-    fetch('{{ .PathPrefix }}/versions')
+    fetch(`${basePath}/versions`)
         .then(response => response.json())
         .then(versions => {
             var dropdown = document.getElementById('dropdown');
@@ -22,7 +25,7 @@ function setupVersionSelect() {
 
 function handleVersionSelect() {
     const version = document.getElementById('dropdown').value;
-    fetch(`{{ .PathPrefix }}/version/${version}/roles`)
+    fetch(`${basePath}/version/${version}/roles`)
         .then(response => response.json())
         .then(roles => {
             buttonContainer = document.getElementById('button-container');
@@ -32,7 +35,7 @@ function handleVersionSelect() {
                 button.innerHTML = role;
 
                 button.onclick = function () {
-                    window.location.href = `{{ .PathPrefix }}/${version}/${role}`;
+                    window.location.href = `${basePath}/${version}/${role}`;
                 };
 
                 buttonContainer.appendChild(button);
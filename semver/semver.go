@@ -0,0 +1,123 @@
+// Package semver parses and compares semantic versions such as v1.2.3.
+package semver
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version.
+type Version struct {
+	Major, Minor, Patch int
+	// The dot separated identifiers of the prerelease, empty if it is not a prerelease
+	Prerelease []string
+}
+
+// Parse parses a version such as v1.2.3 or 1.2.3-rc.1+build.
+// The patch part is optional and defaults to 0. The minor part is required
+// so that plain numbers, such as a year or a build number, are not versions.
+func Parse(s string) (Version, bool) {
+	s = strings.TrimPrefix(s, "v")
+
+	// Build metadata does not affect the precedence
+	s, _, _ = strings.Cut(s, "+")
+
+	s, pre, hasPre := strings.Cut(s, "-")
+	if hasPre && pre == "" {
+		return Version{}, false
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, false
+	}
+
+	var nums [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || part[0] == '+' {
+			return Version{}, false
+		}
+		nums[i] = n
+	}
+
+	v := Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}
+	if hasPre {
+		v.Prerelease = strings.Split(pre, ".")
+	}
+
+	return v, true
+}
+
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Compare compares two versions according to the semver precedence rules.
+func Compare(a, b Version) int {
+	if c := cmp.Compare(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+
+	// A prerelease has lower precedence than the release
+	switch {
+	case !a.IsPrerelease() && !b.IsPrerelease():
+		return 0
+	case !a.IsPrerelease():
+		return 1
+	case !b.IsPrerelease():
+		return -1
+	}
+
+	for i := 0; i < len(a.Prerelease) && i < len(b.Prerelease); i++ {
+		if c := comparePrereleaseIdentifier(a.Prerelease[i], b.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(len(a.Prerelease), len(b.Prerelease))
+}
+
+// CompareNamesNewestFirst orders names that are semantic versions with the newest first,
+// followed by the names that are not semantic versions, which are equal to each other
+// so that a stable sort keeps them in their original order.
+func CompareNamesNewestFirst(a, b string) int {
+	av, aOk := Parse(a)
+	bv, bOk := Parse(b)
+
+	switch {
+	case aOk && bOk:
+		return Compare(bv, av)
+	case aOk:
+		return -1
+	case bOk:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePrereleaseIdentifier compares numeric identifiers numerically and others lexically,
+// numeric identifiers always have lower precedence.
+func comparePrereleaseIdentifier(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+
+	switch {
+	case aErr == nil && bErr == nil:
+		return cmp.Compare(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
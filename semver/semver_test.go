@@ -0,0 +1,82 @@
+package semver
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   Version
+		wantOk bool
+	}{
+		{in: "v1.2.3", want: Version{Major: 1, Minor: 2, Patch: 3}, wantOk: true},
+		{in: "1.2.3", want: Version{Major: 1, Minor: 2, Patch: 3}, wantOk: true},
+		{in: "v1.2", want: Version{Major: 1, Minor: 2}, wantOk: true},
+		{in: "1.2.3-rc.1+build.5", want: Version{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"rc", "1"}}, wantOk: true},
+		{in: "2024"},
+		{in: "v2"},
+		{in: "1.2.3.4"},
+		{in: "1.2.3-"},
+		{in: "1.-2.3"},
+		{in: "1.+2.3"},
+		{in: "main"},
+		{in: "release/1.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := Parse(tt.in)
+			if ok != tt.wantOk {
+				t.Fatalf("got ok %t, want %t", ok, tt.wantOk)
+			}
+
+			if got.Major != tt.want.Major || got.Minor != tt.want.Minor || got.Patch != tt.want.Patch || !slices.Equal(got.Prerelease, tt.want.Prerelease) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Ordered from the lowest to the highest precedence, as in the examples of the specification
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.2.0",
+		"2.0.0",
+	}
+
+	for i := 1; i < len(ordered); i++ {
+		a, _ := Parse(ordered[i-1])
+		b, _ := Parse(ordered[i])
+
+		if Compare(a, b) >= 0 || Compare(b, a) <= 0 {
+			t.Errorf("expected %s < %s", ordered[i-1], ordered[i])
+		}
+	}
+
+	a, _ := Parse("v1.0.0+build.1")
+	b, _ := Parse("1.0.0+build.2")
+	if Compare(a, b) != 0 {
+		t.Error("expected the build metadata to be ignored")
+	}
+}
+
+func TestCompareNamesNewestFirst(t *testing.T) {
+	names := []string{"main", "v1.0.0", "latest", "v2.0.0-rc.1", "v1.10.0", "2024", "v2.0.0"}
+	slices.SortStableFunc(names, CompareNamesNewestFirst)
+
+	want := []string{"v2.0.0", "v2.0.0-rc.1", "v1.10.0", "v1.0.0", "main", "latest", "2024"}
+	if !slices.Equal(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	accessCheckTimeout = 30 * time.Second
+)
+
+// AccessChecker is implemented by providers whose links from GetPath
+// might not be reachable by the browser, such as for private repositories.
+type AccessChecker interface {
+	// RequiresProxy reports if the browser can not fetch the files from the links of GetPath.
+	RequiresProxy(ctx context.Context) (bool, error)
+}
+
+// RequiresProxy checks if the files of the provider have to be served through the proxy.
+// If it can not be determined, the proxy is required so that no unreachable links are given to the browser.
+// It is meant to be called once when setting up, with the result passed to New in the config.
+func RequiresProxy(ctx context.Context, provider Provider) bool {
+	checker, ok := provider.(AccessChecker)
+	if !ok {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, accessCheckTimeout)
+	defer cancel()
+
+	required, err := checker.RequiresProxy(ctx)
+	switch {
+	case isRateLimited(err):
+		slog.Warn("rate limited while checking if the repository is public, the proxy is forced until restarted, enable it in the config to skip the check", "error", err)
+		return true
+	case err != nil:
+		slog.Warn("failed to check if the repository is public, the proxy is forced until restarted, enable it in the config to skip the check", "error", err)
+		return true
+	case required:
+		slog.Info("the repository is not public, the proxy is forced")
+	}
+
+	return required
+}
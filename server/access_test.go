@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/theleeeo/docs-server/provider"
+)
+
+type accessCheckingProvider struct {
+	Provider
+	private bool
+	err     error
+}
+
+func (p accessCheckingProvider) RequiresProxy(ctx context.Context) (bool, error) {
+	return p.private, p.err
+}
+
+func TestRequiresProxy(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		want     bool
+	}{
+		{name: "can not be checked", provider: struct{ Provider }{}},
+		{name: "public", provider: accessCheckingProvider{}},
+		{name: "private", provider: accessCheckingProvider{private: true}, want: true},
+		{name: "failed", provider: accessCheckingProvider{err: errors.New("connection refused")}, want: true},
+		{
+			name:     "rate limited",
+			provider: accessCheckingProvider{err: provider.RateLimitError{Message: "rate limit reached", Reset: time.Now().Add(time.Hour)}},
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RequiresProxy(context.Background(), tt.provider); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
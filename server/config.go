@@ -1,8 +1,29 @@
 package server
 
-import "time"
+import (
+	"time"
+
+	"github.com/theleeeo/docs-server/cache"
+)
 
 type Config struct {
 	PollInterval time.Duration
 	Proxy        bool
+	// The limits of the cache used by the proxy
+	Cache cache.Config
+	// Download all files of a version into the cache when it is found
+	Prefetch bool
+	// The maximum number of files being prefetched at the same time
+	PrefetchConcurrency int
+	// Watch the provider for changes if it supports it
+	Watch bool
+
+	// Only serve the versions matching this regular expression
+	Include string
+	// Do not serve the versions matching this regular expression
+	Exclude string
+	// Do not serve semantic versions that are prereleases
+	HidePrereleases bool
+	// Only serve the newest patch releases of each minor release, 0 keeps all of them
+	KeepPatches int
 }
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// The longest a shared download may take, since it is not canceled by the callers
+	flightTimeout = time.Minute
+)
+
+// flightGroup coalesces concurrent calls with the same key into a single call.
+type flightGroup struct {
+	lock  sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done chan struct{}
+
+	data []byte
+	err  error
+}
+
+// do calls fn unless a call with the same key is already in flight, in which case it waits for that one instead.
+// The call is detached from the context of the caller so that one caller giving up does not fail the others,
+// but every caller stops waiting when its own context is done.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	g.lock.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	c, ok := g.calls[key]
+	if !ok {
+		c = &flightCall{done: make(chan struct{})}
+		g.calls[key] = c
+
+		go func() {
+			callCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flightTimeout)
+			defer cancel()
+
+			c.data, c.err = fn(callCtx)
+
+			g.lock.Lock()
+			delete(g.calls, key)
+			g.lock.Unlock()
+
+			close(c.done)
+		}()
+	}
+	g.lock.Unlock()
+
+	select {
+	case <-c.done:
+		return c.data, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
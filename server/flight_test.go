@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingProvider blocks the downloads until it is released.
+type blockingProvider struct {
+	Provider
+	started   chan struct{}
+	release   chan struct{}
+	downloads atomic.Int32
+}
+
+func (p *blockingProvider) DownloadFile(ctx context.Context, version, file string) ([]byte, error) {
+	if p.downloads.Add(1) == 1 {
+		close(p.started)
+	}
+
+	select {
+	case <-p.release:
+		return []byte(version + "/" + file), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestGetFileSharesDownloads(t *testing.T) {
+	p := &blockingProvider{started: make(chan struct{}), release: make(chan struct{})}
+	s, err := New(&Config{}, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first caller starts the download and gives up before it is done
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := s.GetFile(ctx, "v1", "api")
+		firstErr <- err
+	}()
+	<-p.started
+
+	const n = 10
+	var wg sync.WaitGroup
+	results := make([][]byte, n)
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = s.GetFile(context.Background(), "v1", "api")
+		}()
+	}
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v for the canceled caller, want %v", err, context.Canceled)
+	}
+
+	// Give the callers time to join the download before it completes
+	time.Sleep(100 * time.Millisecond)
+	close(p.release)
+	wg.Wait()
+
+	if got := p.downloads.Load(); got != 1 {
+		t.Errorf("got %d downloads, want 1", got)
+	}
+
+	for i := range n {
+		if errs[i] != nil {
+			t.Errorf("got error %v for caller %d", errs[i], i)
+			continue
+		}
+		if string(results[i]) != "v1/api" {
+			t.Errorf("got %q for caller %d, want %q", results[i], i, "v1/api")
+		}
+	}
+}
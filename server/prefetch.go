@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/theleeeo/docs-server/cache"
+)
+
+const (
+	defaultPrefetchConcurrency = 4
+)
+
+// VersionCacheStatus is how much of a version is in the proxy cache.
+type VersionCacheStatus struct {
+	Version     string `json:"version"`
+	Files       int    `json:"files"`
+	Cached      int    `json:"cached"`
+	FullyCached bool   `json:"fully_cached"`
+	// If the files of the version are currently being prefetched
+	Prefetching bool `json:"prefetching"`
+}
+
+// CacheStatus is the state of the proxy cache.
+type CacheStatus struct {
+	Stats    cache.Stats          `json:"stats"`
+	Versions []VersionCacheStatus `json:"versions"`
+}
+
+// prefetch downloads all files of a version into the cache in the background.
+// The number of concurrent downloads is bounded across all versions,
+// and the prefetch of a version is stopped when the provider is rate limited.
+func (s *Server) prefetch(ctx context.Context, version string, files []string) {
+	s.prefetchingLock.Lock()
+	if _, ok := s.prefetching[version]; ok {
+		s.prefetchingLock.Unlock()
+		return
+	}
+	s.prefetching[version] = struct{}{}
+	s.prefetchingLock.Unlock()
+
+	go func() {
+		defer func() {
+			s.prefetchingLock.Lock()
+			delete(s.prefetching, version)
+			s.prefetchingLock.Unlock()
+		}()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		slog.Debug("prefetching version", "version", version, "files", len(files))
+
+		wg := &sync.WaitGroup{}
+		for _, file := range files {
+			if s.cache.Has(version, s.revision(version), file) {
+				continue
+			}
+
+			// The slot is not taken if the prefetch is cancelled while waiting,
+			// or given back if both were ready and the slot won
+			select {
+			case <-ctx.Done():
+			case s.prefetchSem <- struct{}{}:
+				if ctx.Err() != nil {
+					<-s.prefetchSem
+				}
+			}
+			if ctx.Err() != nil {
+				break
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-s.prefetchSem }()
+
+				// A stale copy would hide that the provider is failing
+				if _, err := s.getFile(ctx, version, file); err != nil {
+					if isRateLimited(err) {
+						slog.Warn("rate limited while prefetching, stopping", "version", version, "error", err)
+						cancel()
+						return
+					}
+
+					if ctx.Err() == nil {
+						slog.Warn("failed to prefetch file", "version", version, "file", file, "error", err)
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+		slog.Debug("done prefetching version", "version", version)
+	}()
+}
+
+// CacheStatus returns the state of the proxy cache, or nil if the proxy is disabled.
+func (s *Server) CacheStatus() *CacheStatus {
+	if !s.cfg.Proxy {
+		return nil
+	}
+
+	status := &CacheStatus{
+		Stats: s.cache.Stats(),
+	}
+
+	s.prefetchingLock.Lock()
+	prefetching := make(map[string]struct{}, len(s.prefetching))
+	for v := range s.prefetching {
+		prefetching[v] = struct{}{}
+	}
+	s.prefetchingLock.Unlock()
+
+	for _, version := range s.GetVersions() {
+		doc := s.GetVersion(version)
+		if doc == nil {
+			continue
+		}
+
+		vs := VersionCacheStatus{
+			Version: version,
+			Files:   len(doc.Files),
+		}
+		for _, file := range doc.Files {
+			if s.cache.Has(version, doc.Revision, file) {
+				vs.Cached++
+			}
+		}
+		vs.FullyCached = vs.Cached == vs.Files
+		_, vs.Prefetching = prefetching[version]
+
+		status.Versions = append(status.Versions, vs)
+	}
+
+	return status
+}
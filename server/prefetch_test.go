@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/theleeeo/docs-server/cache"
+)
+
+func TestPrefetchCancelledReleasesSlots(t *testing.T) {
+	s := &Server{
+		cfg:         &Config{Proxy: true},
+		cache:       cache.NewMemory(&cache.Config{}),
+		prefetching: make(map[string]struct{}),
+		prefetchSem: make(chan struct{}, 1),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Both the cancellation and a free slot are ready, whichever wins must not keep the slot
+	for range 20 {
+		s.prefetch(ctx, "v1", []string{"a", "b"})
+
+		deadline := time.Now().Add(time.Second)
+		for {
+			s.prefetchingLock.Lock()
+			_, running := s.prefetching["v1"]
+			s.prefetchingLock.Unlock()
+			if !running {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("the prefetch did not stop")
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		if n := len(s.prefetchSem); n != 0 {
+			t.Fatalf("%d slots were not released", n)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/theleeeo/docs-server/provider"
+)
+
+// RateLimiter is implemented by providers that report their rate limit budget.
+type RateLimiter interface {
+	// RateLimit returns the last rate limit reported by the provider, or false if it has not reported one.
+	RateLimit() (provider.RateLimit, bool)
+}
+
+// RateLimitStatus is the rate limit budget of the provider.
+type RateLimitStatus struct {
+	// The budget as last reported by the provider, nil if it does not report one
+	Budget *provider.RateLimit `json:"budget"`
+	// Until when calls to the provider are paused because of a rate limit, nil if they are not
+	PausedUntil *time.Time `json:"paused_until"`
+}
+
+func isRateLimited(err error) bool {
+	var rateLimitErr provider.RateLimitError
+	return errors.As(err, &rateLimitErr)
+}
+
+// callProvider calls the provider unless calls are paused because of a rate limit.
+// If the call is rate limited, all calls are paused until the rate limit is reset.
+func callProvider[T any](s *Server, call func() (T, error)) (T, error) {
+	if until, paused := s.pausedUntil(); paused {
+		var zero T
+		return zero, provider.RateLimitError{
+			Message: "calls to the provider are paused until the rate limit is reset",
+			Reset:   until,
+		}
+	}
+
+	result, err := call()
+
+	var rateLimitErr provider.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		if retryAt, ok := rateLimitErr.RetryAt(); ok {
+			s.pauseUntil(retryAt)
+		}
+	}
+
+	return result, err
+}
+
+func (s *Server) pausedUntil() (time.Time, bool) {
+	s.rateLimitLock.Lock()
+	defer s.rateLimitLock.Unlock()
+
+	return s.paused, time.Now().Before(s.paused)
+}
+
+func (s *Server) pauseUntil(t time.Time) {
+	s.rateLimitLock.Lock()
+	defer s.rateLimitLock.Unlock()
+
+	if t.After(s.paused) {
+		slog.Warn("rate limited, pausing calls to the provider", "until", t)
+		s.paused = t
+	}
+}
+
+// RateLimitStatus returns the rate limit budget of the provider and if calls to it are paused.
+func (s *Server) RateLimitStatus() RateLimitStatus {
+	var status RateLimitStatus
+
+	if rl, ok := s.provider.(RateLimiter); ok {
+		if budget, ok := rl.RateLimit(); ok {
+			status.Budget = &budget
+		}
+	}
+
+	if until, paused := s.pausedUntil(); paused {
+		status.PausedUntil = &until
+	}
+
+	return status
+}
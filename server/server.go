@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"regexp"
 	"slices"
 	"sync"
 	"time"
@@ -15,6 +16,9 @@ import (
 
 var (
 	defaultPollInterval = 15 * time.Minute
+	pollTriggerDelay    = 2 * time.Second
+	// The delay before the first retry of a failed poll, doubled for every failure after that
+	pollRetryDelay = 5 * time.Second
 )
 
 var (
@@ -22,7 +26,8 @@ var (
 )
 
 type Provider interface {
-	ListVersions(ctx context.Context) ([]string, error)
+	// ListVersions may return provider.ErrNotModified if the versions did not change since the last call
+	ListVersions(ctx context.Context) ([]provider.Version, error)
 	ListFiles(ctx context.Context, version string) ([]string, error)
 	GetPath(version, file string) string
 	DownloadFile(ctx context.Context, version, file string) ([]byte, error)
@@ -34,22 +39,70 @@ func validateConfig(cfg *Config) error {
 		cfg.PollInterval = defaultPollInterval
 	}
 
+	if cfg.Prefetch && !cfg.Proxy {
+		slog.Warn("prefetching requires the proxy, it will be disabled")
+		cfg.Prefetch = false
+	}
+
+	if cfg.Prefetch && cfg.PrefetchConcurrency <= 0 {
+		slog.Info("no prefetch concurrency set, using default", "default", defaultPrefetchConcurrency)
+		cfg.PrefetchConcurrency = defaultPrefetchConcurrency
+	}
+
+	if cfg.KeepPatches < 0 {
+		return fmt.Errorf("keep patches cannot be negative")
+	}
+
 	return nil
 }
 
+func compileOptionalRegexp(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	return regexp.Compile(pattern)
+}
+
 func New(cfg *Config, provider Provider) (*Server, error) {
 	if err := validateConfig(cfg); err != nil {
 		return nil, fmt.Errorf("failed to validate config: %w", err)
 	}
 
+	include, err := compileOptionalRegexp(cfg.Include)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %w", err)
+	}
+
+	exclude, err := compileOptionalRegexp(cfg.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+
 	s := &Server{
-		provider: provider,
-		cfg:      cfg,
+		provider:    provider,
+		cfg:         cfg,
+		subscribers: make(map[chan string]struct{}),
+		pollTrigger: make(chan struct{}, 1),
+		include:     include,
+		exclude:     exclude,
+		prefetching: make(map[string]struct{}),
+		prefetchSem: make(chan struct{}, max(cfg.PrefetchConcurrency, 1)),
+	}
+
+	if cfg.Watch {
+		if _, ok := provider.(Watcher); !ok {
+			slog.Warn("the provider does not support watching, only polling will be used")
+		}
 	}
 
 	if cfg.Proxy {
-		slog.Info("using proxy for caching files")
-		s.cache = cache.New()
+		slog.Info("using proxy for caching files", "max_bytes", cfg.Cache.MaxBytes, "max_entries", cfg.Cache.MaxEntries, "ttl", cfg.Cache.TTL)
+		c, err := cache.New(&cfg.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cache: %w", err)
+		}
+		s.cache = c
 	}
 
 	return s, nil
@@ -57,18 +110,49 @@ func New(cfg *Config, provider Provider) (*Server, error) {
 
 type Server struct {
 	provider Provider
-	cache    *cache.Cache
+	cache    cache.Cache
 
 	cfg *Config
 
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+
 	docsRWLock sync.RWMutex
 	// The documentation files and their versions that are available
 	docs []*Documentation
+	// The versions found by the last poll that listed them
+	found []provider.Version
+
+	subscribersLock sync.Mutex
+	// Channels that are notified when a version changes while watching
+	subscribers map[chan string]struct{}
+
+	// Used to request a poll outside of the poll interval
+	pollTrigger chan struct{}
+
+	// The downloads of files that are in flight
+	downloads flightGroup
+
+	prefetchingLock sync.Mutex
+	// The versions currently being prefetched
+	prefetching map[string]struct{}
+	// Bounds the number of concurrent prefetch downloads
+	prefetchSem chan struct{}
+
+	rateLimitLock sync.Mutex
+	// Calls to the provider are paused until this time because of a rate limit
+	paused time.Time
 }
 
 type Documentation struct {
 	// The version of this documentation
 	Version string
+	// The revision of the version when the files were listed, empty if unknown
+	Revision string
+	// If the version is expected to change, such as a branch
+	Moving bool
+	// When the files of the version were last listed
+	Updated time.Time
 	// The different files in this version
 	Files []string
 }
@@ -81,39 +165,107 @@ func (s *Server) ProxyEnabled() bool {
 	return s.cfg.Proxy
 }
 
+// ServedByProxy reports if a file has to be served through the proxy.
+// That is the case if the proxy is enabled or if the provider has no path
+// to the file that can be reached by the browser.
+func (s *Server) ServedByProxy(version, file string) bool {
+	return s.cfg.Proxy || s.provider.GetPath(version, file) == ""
+}
+
+// GetFile returns a file, from the cache if the proxy is enabled.
+// If the file can not be downloaded, the last good copy in the cache is returned even if it has expired.
 func (s *Server) GetFile(ctx context.Context, version, file string) ([]byte, error) {
-	// TODO: Why even try to get from cache if proxy is disabled?
-	data, err := s.cache.Get(version, file)
-	if err != nil {
-		return nil, err
-	}
-	if data != nil {
-		return data, nil
+	data, err := s.getFile(ctx, version, file)
+	if err == nil || !s.cfg.Proxy || errors.Is(err, ErrNotFound) {
+		return data, err
 	}
 
-	data, err = s.provider.DownloadFile(ctx, version, file)
-	if err != nil {
-		if errors.Is(err, provider.ErrNotFound) {
-			return nil, ErrNotFound
-		}
+	stale, cacheErr := s.cache.GetStale(version, file)
+	if cacheErr != nil || stale == nil {
 		return nil, err
 	}
 
+	slog.Warn("failed to get file, serving stale copy from cache", "version", version, "file", file, "error", err)
+	return stale, nil
+}
+
+func (s *Server) getFile(ctx context.Context, version, file string) ([]byte, error) {
+	revision := s.revision(version)
+
 	if s.cfg.Proxy {
-		err := s.cache.Set(version, file, data)
+		data, err := s.cache.Get(version, revision, file)
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			return data, nil
+		}
+	}
+
+	// Concurrent requests for the same file share a single download
+	return s.downloads.do(ctx, getFlightKey(version, revision, file), func(ctx context.Context) ([]byte, error) {
+		data, err := callProvider(s, func() ([]byte, error) {
+			return s.provider.DownloadFile(ctx, version, file)
+		})
 		if err != nil {
-			slog.Warn("failed to save file to disk", "error", err)
+			if errors.Is(err, provider.ErrNotFound) {
+				return nil, ErrNotFound
+			}
+			return nil, err
+		}
+
+		if s.cfg.Proxy {
+			err := s.cache.Set(version, revision, file, data)
+			if err != nil {
+				slog.Warn("failed to save file to cache", "error", err)
+			}
 		}
+
+		return data, nil
+	})
+}
+
+func getFlightKey(version, revision, file string) string {
+	return fmt.Sprint(version, "@", revision, "/", file)
+}
+
+// revision returns the revision the version was last fetched at,
+// cached files of any other revision are outdated.
+func (s *Server) revision(version string) string {
+	if doc := s.GetVersion(version); doc != nil {
+		return doc.Revision
 	}
 
-	return data, nil
+	return ""
 }
 
 func (s *Server) Run(ctx context.Context) {
 	slog.Info("starting server")
 
-	if err := s.Poll(ctx); err != nil {
-		slog.Error("initial poll failed, will retry on next cycle", "error", err)
+	// A failed poll is retried with backoff instead of waiting for the next poll interval
+	retry := time.NewTimer(0)
+	retry.Stop()
+	defer retry.Stop()
+
+	failures := 0
+	poll := func(reason string) {
+		err := s.Poll(ctx)
+		if err == nil {
+			failures = 0
+			retry.Stop()
+			return
+		}
+
+		failures++
+		delay := s.pollRetryDelay(err, failures)
+		slog.Error(reason+" failed, retrying", "error", err, "attempt", failures, "retry_in", delay)
+		retry.Reset(delay)
+	}
+
+	poll("initial poll")
+
+	if s.Watching() {
+		go s.watch(ctx, s.provider.(Watcher))
 	}
 
 	ticker := time.NewTicker(s.cfg.PollInterval)
@@ -124,28 +276,103 @@ func (s *Server) Run(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := s.Poll(ctx); err != nil {
-				slog.Error("poll failed, will retry on next cycle", "error", err)
+			// A pending retry will poll soon enough
+			if failures > 0 {
+				continue
+			}
+			poll("poll")
+		case <-retry.C:
+			poll("retried poll")
+			ticker.Reset(s.cfg.PollInterval)
+		case <-s.pollTrigger:
+			// Wait a moment so that a burst of triggers results in a single poll
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollTriggerDelay):
 			}
+			select {
+			case <-s.pollTrigger:
+			default:
+			}
+
+			slog.Debug("poll triggered")
+			poll("triggered poll")
+			ticker.Reset(s.cfg.PollInterval)
 		}
 	}
 }
 
+// pollRetryDelay returns how long to wait before retrying a failed poll.
+// A rate limited poll is retried when the rate limit is lifted, other failures
+// are retried with an exponential backoff capped at the poll interval.
+func (s *Server) pollRetryDelay(err error, failures int) time.Duration {
+	var rateLimitErr provider.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		if retryAt, ok := rateLimitErr.RetryAt(); ok {
+			return max(time.Until(retryAt), 0) + time.Second
+		}
+	}
+
+	delay := pollRetryDelay << min(failures-1, 16)
+	return min(delay, s.cfg.PollInterval)
+}
+
+// TriggerPoll requests a poll as soon as possible without waiting for the poll interval.
+// Requests made while a poll is already pending are coalesced into that one.
+func (s *Server) TriggerPoll() {
+	select {
+	case s.pollTrigger <- struct{}{}:
+	default:
+	}
+}
+
 // Poll polls the provider for new versions and files.
+// Versions whose revision changed since they were fetched are fetched again.
+// Polling stops at the first rate limited request since the following ones would fail as well.
 func (s *Server) Poll(ctx context.Context) error {
-	versions, err := s.provider.ListVersions(ctx)
-	if err != nil {
+	versions, err := callProvider(s, func() ([]provider.Version, error) {
+		return s.provider.ListVersions(ctx)
+	})
+	switch {
+	case errors.Is(err, provider.ErrNotModified):
+		// Versions that failed to be fetched last time are still retried
+		slog.Debug("versions not modified since the last poll")
+		versions = s.lastFound()
+	case err != nil:
 		return fmt.Errorf("failed to list versions: %w", err)
+	default:
+		versions = s.filterVersions(versions)
+		s.setLastFound(versions)
 	}
 
-	newVersions, removedVersions := s.calculateVersionDiffs(versions)
+	newVersions, changedVersions, removedVersions := s.calculateVersionDiffs(versions)
 
 	for _, version := range newVersions {
-		slog.Info("found new version", "version", version)
+		slog.Info("found new version", "version", version.Name)
+		if err := s.FetchVersion(ctx, version); err != nil {
+			if isRateLimited(err) {
+				return fmt.Errorf("failed to fetch version %s: %w", version.Name, err)
+			}
+			slog.Error("failed to fetch version, skipping", "version", version.Name, "error", err)
+			continue
+		}
+
+		s.prefetchVersion(ctx, version.Name)
+	}
+
+	for _, version := range changedVersions {
+		slog.Info("version changed", "version", version.Name, "revision", version.Revision)
 		if err := s.FetchVersion(ctx, version); err != nil {
-			slog.Error("failed to fetch version, skipping", "version", version, "error", err)
+			if isRateLimited(err) {
+				return fmt.Errorf("failed to fetch changed version %s: %w", version.Name, err)
+			}
+			slog.Error("failed to fetch changed version, skipping", "version", version.Name, "error", err)
 			continue
 		}
+
+		// The cached files of the old revision are kept as stale copies until they are replaced
+		s.prefetchVersion(ctx, version.Name)
 	}
 
 	for _, version := range removedVersions {
@@ -156,8 +383,25 @@ func (s *Server) Poll(ctx context.Context) error {
 	return nil
 }
 
-func (s *Server) FetchVersion(ctx context.Context, version string) error {
-	files, err := s.provider.ListFiles(ctx, version)
+func (s *Server) lastFound() []provider.Version {
+	s.docsRWLock.RLock()
+	defer s.docsRWLock.RUnlock()
+
+	return s.found
+}
+
+func (s *Server) setLastFound(versions []provider.Version) {
+	s.docsRWLock.Lock()
+	defer s.docsRWLock.Unlock()
+
+	s.found = versions
+}
+
+// FetchVersion lists the files of a version and adds or updates it.
+func (s *Server) FetchVersion(ctx context.Context, version provider.Version) error {
+	files, err := callProvider(s, func() ([]string, error) {
+		return s.provider.ListFiles(ctx, version.Name)
+	})
 	if err != nil {
 		return err
 	}
@@ -167,68 +411,98 @@ func (s *Server) FetchVersion(ctx context.Context, version string) error {
 
 	// If the version already exists, update the files
 	for _, d := range s.docs {
-		if d.Version == version {
+		if d.Version == version.Name {
 			d.Files = files
+			d.Revision = version.Revision
+			d.Moving = version.Moving
+			d.Updated = time.Now()
 			return nil
 		}
 	}
 
 	// Otherwise, append a new version
 	s.docs = append(s.docs, &Documentation{
-		Version: version,
-		Files:   files,
+		Version:  version.Name,
+		Revision: version.Revision,
+		Moving:   version.Moving,
+		Updated:  time.Now(),
+		Files:    files,
 	})
 
 	return nil
 }
 
+// prefetchVersion starts prefetching the files of a version if it is enabled.
+func (s *Server) prefetchVersion(ctx context.Context, version string) {
+	if !s.cfg.Prefetch {
+		return
+	}
+
+	if doc := s.GetVersion(version); doc != nil {
+		s.prefetch(ctx, version, doc.Files)
+	}
+}
+
 func (s *Server) RemoveVersion(version string) {
 	s.docsRWLock.Lock()
-	defer s.docsRWLock.Unlock()
-
 	for i, d := range s.docs {
 		if d.Version == version {
 			s.docs = append(s.docs[:i], s.docs[i+1:]...)
 			break
 		}
 	}
+	s.docsRWLock.Unlock()
+
+	if s.cfg.Proxy {
+		s.cache.RemoveVersion(version)
+	}
 }
 
 // calculateVersionDiffs calculates the differences between the currently
 // available versions and the ones that were found by the provider.
-func (s *Server) calculateVersionDiffs(foundVersions []string) (newVersions []string, removedVersions []string) {
+// A version has changed if the provider reports a revision different from the one it was fetched at.
+func (s *Server) calculateVersionDiffs(foundVersions []provider.Version) (newVersions, changedVersions []provider.Version, removedVersions []string) {
 	foundVersionsMap := make(map[string]struct{}, len(foundVersions))
-	for _, t := range foundVersions {
-		foundVersionsMap[t] = struct{}{}
+	for _, v := range foundVersions {
+		foundVersionsMap[v.Name] = struct{}{}
 	}
 
 	s.docsRWLock.RLock()
+	defer s.docsRWLock.RUnlock()
+
 	for _, d := range s.docs {
 		if _, ok := foundVersionsMap[d.Version]; !ok {
 			removedVersions = append(removedVersions, d.Version)
 		}
 	}
-	s.docsRWLock.RUnlock()
 
-	for _, t := range foundVersions {
-		if !slices.ContainsFunc(s.docs, func(d *Documentation) bool {
-			return d.Version == t
-		}) {
-			newVersions = append(newVersions, t)
+	for _, v := range foundVersions {
+		i := slices.IndexFunc(s.docs, func(d *Documentation) bool {
+			return d.Version == v.Name
+		})
+		if i == -1 {
+			newVersions = append(newVersions, v)
+			continue
+		}
+
+		if v.Revision != "" && v.Revision != s.docs[i].Revision {
+			changedVersions = append(changedVersions, v)
 		}
 	}
 
-	return newVersions, removedVersions
+	return newVersions, changedVersions, removedVersions
 }
 
+// GetVersions returns the available versions with the newest first.
 func (s *Server) GetVersions() []string {
 	s.docsRWLock.RLock()
-	defer s.docsRWLock.RUnlock()
-
 	versions := make([]string, len(s.docs))
 	for i, d := range s.docs {
 		versions[i] = d.Version
 	}
+	s.docsRWLock.RUnlock()
+
+	sortVersions(versions)
 
 	return versions
 }
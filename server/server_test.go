@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/theleeeo/docs-server/provider"
+)
+
+// revisionProvider serves a single version at the current revision, failing the downloads if set.
+type revisionProvider struct {
+	revision    string
+	downloadErr error
+}
+
+func (p *revisionProvider) ListVersions(ctx context.Context) ([]provider.Version, error) {
+	return []provider.Version{{Name: "main", Revision: p.revision}}, nil
+}
+
+func (p *revisionProvider) ListFiles(ctx context.Context, version string) ([]string, error) {
+	return []string{"api"}, nil
+}
+
+func (p *revisionProvider) GetPath(version, file string) string {
+	return ""
+}
+
+func (p *revisionProvider) DownloadFile(ctx context.Context, version, file string) ([]byte, error) {
+	if p.downloadErr != nil {
+		return nil, p.downloadErr
+	}
+	return []byte(p.revision), nil
+}
+
+func TestGetFileServesStaleCopyOfChangedVersion(t *testing.T) {
+	p := &revisionProvider{revision: "a"}
+	s, err := New(&Config{Proxy: true}, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetFile(context.Background(), "main", "api"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The version changes but the new revision can not be downloaded
+	p.revision = "b"
+	p.downloadErr = errors.New("connection refused")
+	if err := s.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.revision("main"); got != "b" {
+		t.Fatalf("got revision %q, want the changed one", got)
+	}
+
+	data, err := s.GetFile(context.Background(), "main", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a" {
+		t.Errorf("got %q, want the stale copy of the old revision", data)
+	}
+}
+
+// unchangedProvider lists its versions once and reports them as not modified after that.
+type unchangedProvider struct {
+	revisionProvider
+	listed       bool
+	listFilesErr error
+}
+
+func (p *unchangedProvider) ListVersions(ctx context.Context) ([]provider.Version, error) {
+	if p.listed {
+		return nil, provider.ErrNotModified
+	}
+	p.listed = true
+	return p.revisionProvider.ListVersions(ctx)
+}
+
+func (p *unchangedProvider) ListFiles(ctx context.Context, version string) ([]string, error) {
+	if p.listFilesErr != nil {
+		return nil, p.listFilesErr
+	}
+	return p.revisionProvider.ListFiles(ctx, version)
+}
+
+func TestPollNotModifiedRetriesLastFound(t *testing.T) {
+	p := &unchangedProvider{revisionProvider: revisionProvider{revision: "a"}, listFilesErr: errors.New("connection refused")}
+	s, err := New(&Config{}, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.GetVersions(); len(got) != 0 {
+		t.Fatalf("got versions %v, want none since the files could not be listed", got)
+	}
+
+	// The versions are not modified, but the one that failed is fetched again
+	p.listFilesErr = nil
+	if err := s.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.GetVersions(); !slices.Equal(got, []string{"main"}) {
+		t.Errorf("got versions %v, want %v", got, []string{"main"})
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/theleeeo/docs-server/provider"
+	"github.com/theleeeo/docs-server/semver"
+)
+
+const (
+	// LatestAlias resolves to the newest version, including prereleases
+	LatestAlias = "latest"
+	// LatestStableAlias resolves to the newest version that is not a prerelease
+	LatestStableAlias = "latest-stable"
+)
+
+// filterVersions removes the versions that should not be served according to the config.
+func (s *Server) filterVersions(versions []provider.Version) []provider.Version {
+	var filtered []provider.Version
+	for _, v := range versions {
+		if s.include != nil && !s.include.MatchString(v.Name) {
+			continue
+		}
+
+		if s.exclude != nil && s.exclude.MatchString(v.Name) {
+			continue
+		}
+
+		if s.cfg.HidePrereleases {
+			if sv, ok := semver.Parse(v.Name); ok && sv.IsPrerelease() {
+				continue
+			}
+		}
+
+		filtered = append(filtered, v)
+	}
+
+	if s.cfg.KeepPatches > 0 {
+		filtered = keepLatestPatches(filtered, s.cfg.KeepPatches)
+	}
+
+	return filtered
+}
+
+// keepLatestPatches keeps only the n newest releases of each major.minor release.
+// Prereleases do not count towards the limit, they are kept if they are newer than the oldest kept release.
+// Versions that are not semantic versions are always kept.
+func keepLatestPatches(versions []provider.Version, n int) []provider.Version {
+	byMinor := make(map[string][]semver.Version)
+	for _, v := range versions {
+		if sv, ok := semver.Parse(v.Name); ok && !sv.IsPrerelease() {
+			key := fmt.Sprint(sv.Major, ".", sv.Minor)
+			byMinor[key] = append(byMinor[key], sv)
+		}
+	}
+
+	// The oldest release of each minor release that should be kept
+	oldestKept := make(map[string]semver.Version, len(byMinor))
+	for key, svs := range byMinor {
+		slices.SortFunc(svs, func(a, b semver.Version) int {
+			return semver.Compare(b, a)
+		})
+		oldestKept[key] = svs[min(n, len(svs))-1]
+	}
+
+	var kept []provider.Version
+	for _, v := range versions {
+		sv, ok := semver.Parse(v.Name)
+		if ok {
+			// Minor releases with only prereleases have nothing to compare against
+			oldest, hasReleases := oldestKept[fmt.Sprint(sv.Major, ".", sv.Minor)]
+			if hasReleases && semver.Compare(sv, oldest) < 0 {
+				continue
+			}
+		}
+
+		kept = append(kept, v)
+	}
+
+	return kept
+}
+
+// sortVersions sorts the versions with the newest semantic version first.
+// Versions that are not semantic versions are placed last in their original order.
+func sortVersions(versions []string) {
+	slices.SortStableFunc(versions, semver.CompareNamesNewestFirst)
+}
+
+// ResolveVersion resolves an alias such as "latest" to the version it points to.
+// Versions that are not aliases, or aliases that can not be resolved, are returned as they are.
+// A version with the same name as an alias takes precedence over the alias.
+func (s *Server) ResolveVersion(version string) string {
+	if version != LatestAlias && version != LatestStableAlias {
+		return version
+	}
+
+	versions := s.GetVersions()
+	if slices.Contains(versions, version) {
+		return version
+	}
+
+	for _, v := range versions {
+		sv, ok := semver.Parse(v)
+		if !ok {
+			continue
+		}
+
+		if version == LatestStableAlias && sv.IsPrerelease() {
+			continue
+		}
+
+		return v
+	}
+
+	// Without any semantic versions the first version is the latest
+	if version == LatestAlias && len(versions) > 0 {
+		return versions[0]
+	}
+
+	return version
+}
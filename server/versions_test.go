@@ -0,0 +1,66 @@
+package server
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/theleeeo/docs-server/provider"
+)
+
+func TestKeepLatestPatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []string
+		n        int
+		want     []string
+	}{
+		{
+			name:     "keeps the newest patches of each minor",
+			versions: []string{"v1.2.3", "v1.2.2", "v1.2.1", "v1.1.0", "main"},
+			n:        2,
+			want:     []string{"v1.2.3", "v1.2.2", "v1.1.0", "main"},
+		},
+		{
+			name:     "a newer prerelease does not hide the release",
+			versions: []string{"v1.2.4-rc.1", "v1.2.3", "v1.2.2"},
+			n:        1,
+			want:     []string{"v1.2.4-rc.1", "v1.2.3"},
+		},
+		{
+			name:     "prereleases older than the kept releases are hidden",
+			versions: []string{"v1.2.3", "v1.2.3-rc.2", "v1.2.2"},
+			n:        1,
+			want:     []string{"v1.2.3"},
+		},
+		{
+			name:     "prereleases of an unreleased minor are kept",
+			versions: []string{"v1.3.0-rc.2", "v1.3.0-rc.1", "v1.2.0"},
+			n:        1,
+			want:     []string{"v1.3.0-rc.2", "v1.3.0-rc.1", "v1.2.0"},
+		},
+		{
+			name:     "plain numbers are not versions",
+			versions: []string{"2024", "2023", "v1.0.1", "v1.0.0"},
+			n:        1,
+			want:     []string{"2024", "2023", "v1.0.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var versions []provider.Version
+			for _, name := range tt.versions {
+				versions = append(versions, provider.Version{Name: name})
+			}
+
+			var got []string
+			for _, v := range keepLatestPatches(versions, tt.n) {
+				got = append(got, v.Name)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
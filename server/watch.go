@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// How long to wait for more changes before handling them,
+	// editors often write a file in several steps.
+	watchDebounce = 200 * time.Millisecond
+)
+
+// Watcher is implemented by providers that can notify about changes
+// instead of relying on the poll interval.
+type Watcher interface {
+	// Watch blocks until the context is canceled and calls changed with the version that changed.
+	// An empty version means that the list of versions might have changed.
+	Watch(ctx context.Context, changed func(version string)) error
+}
+
+// Watching reports if the server is watching the provider for changes.
+func (s *Server) Watching() bool {
+	if !s.cfg.Watch {
+		return false
+	}
+	_, ok := s.provider.(Watcher)
+	return ok
+}
+
+// Subscribe returns a channel receiving the versions that changed while watching.
+// An empty version means that the list of versions changed.
+// The returned function must be called to unsubscribe.
+func (s *Server) Subscribe() (<-chan string, func()) {
+	ch := make(chan string, 16)
+
+	s.subscribersLock.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.subscribersLock.Unlock()
+
+	return ch, func() {
+		s.subscribersLock.Lock()
+		delete(s.subscribers, ch)
+		s.subscribersLock.Unlock()
+	}
+}
+
+func (s *Server) publish(version string) {
+	s.subscribersLock.Lock()
+	defer s.subscribersLock.Unlock()
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- version:
+		default:
+			// Slow subscribers miss the event rather than blocking the server
+		}
+	}
+}
+
+func (s *Server) watch(ctx context.Context, w Watcher) {
+	slog.Info("watching provider for changes")
+
+	var (
+		lock    sync.Mutex
+		pending = make(map[string]struct{})
+		timer   *time.Timer
+	)
+
+	flush := func() {
+		lock.Lock()
+		changed := pending
+		pending = make(map[string]struct{})
+		lock.Unlock()
+
+		s.handleChanges(ctx, changed)
+	}
+
+	err := w.Watch(ctx, func(version string) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		pending[version] = struct{}{}
+		if timer == nil {
+			timer = time.AfterFunc(watchDebounce, flush)
+		} else {
+			timer.Reset(watchDebounce)
+		}
+	})
+	if err != nil {
+		slog.Error("failed to watch provider, falling back to polling", "error", err)
+	}
+
+	lock.Lock()
+	if timer != nil {
+		timer.Stop()
+	}
+	lock.Unlock()
+}
+
+// handleChanges polls the provider which picks up the changed versions through their revisions,
+// then notifies the subscribers about the changes.
+func (s *Server) handleChanges(ctx context.Context, changed map[string]struct{}) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err := s.Poll(ctx); err != nil {
+		slog.Error("poll after change failed", "error", err)
+		return
+	}
+
+	for version := range changed {
+		s.publish(version)
+	}
+}